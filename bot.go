@@ -14,7 +14,10 @@ import (
 type GreekMilkBot struct {
 	plugins map[string]*models.PluginInstance
 	route   *Router[models.Packet]
+	options []RouterOption[models.Packet]
 	once    *atomic.Bool
+
+	onDegrade func(plugin string, packet models.Packet, report models.DegradeReport) // 内容降级回调
 }
 
 func NewGreekMilkBot(plugins ...models.Plugin) (*GreekMilkBot, error) {
@@ -23,7 +26,6 @@ func NewGreekMilkBot(plugins ...models.Plugin) (*GreekMilkBot, error) {
 	}
 	r := &GreekMilkBot{
 		plugins: make(map[string]*models.PluginInstance),
-		route:   NewRouter[models.Packet](8),
 		once:    new(atomic.Bool),
 	}
 	for i, plugin := range plugins {
@@ -41,17 +43,67 @@ func NewGreekMilkBot(plugins ...models.Plugin) (*GreekMilkBot, error) {
 	}
 	return r, nil
 }
+
+// WithRouterOptions 追加内部路由器的配置，需在 Run 之前调用
+func (r *GreekMilkBot) WithRouterOptions(opts ...RouterOption[models.Packet]) error {
+	if r.once.Load() {
+		return errors.New("plugin already running")
+	}
+	r.options = append(r.options, opts...)
+	return nil
+}
+
+// OnDegrade 设置内容降级回调，发送给插件的消息包含其不支持的内容并被降级时调用，需在 Run 之前调用
+func (r *GreekMilkBot) OnDegrade(fn func(plugin string, packet models.Packet, report models.DegradeReport)) error {
+	if r.once.Load() {
+		return errors.New("plugin already running")
+	}
+	r.onDegrade = fn
+	return nil
+}
+
 func (r *GreekMilkBot) Run(ctx context.Context) error {
 	if r.once.Swap(true) {
 		return errors.New("plugin already running")
 	}
+	options := append([]RouterOption[models.Packet]{WithPriorityClassifier(packetPriority)}, r.options...)
+	r.route = NewRouter[models.Packet](8, options...)
 	for id, plugin := range r.plugins {
+		route, err := r.route.AddRoute(id)
+		if err != nil {
+			return err
+		}
+		subscriptions := models.NewSubscriptions()
+		sender := func(ctx context.Context, packet models.Packet) error {
+			if packet.Dest == "" {
+				route.SendBroadcastContext(ctx, packet)
+			} else {
+				route.SendContext(ctx, packet.Dest, packet)
+			}
+			return nil
+		}
+		route.HandlerFunc(func(header RoutePacketHeader, packet models.Packet) {
+			if degraded, report := degradePacket(plugin, packet); !report.Empty() {
+				packet = degraded
+				if r.onDegrade != nil {
+					r.onDegrade(id, packet, report)
+				}
+			}
+			dispatchPacket(plugin, models.PluginBus{
+				Context:       ContextWithTrace(ctx, header.Trace),
+				ID:            id,
+				Sender:        sender,
+				Subscriptions: subscriptions,
+			}, packet)
+		})
 		if err := func() error {
 			bootCtx, cancel := context.WithCancel(ctx)
 			defer cancel()
 			plugin.Bus = models.PluginBus{
-				Context: bootCtx,
-				ID:      id,
+				Context:       bootCtx,
+				ID:            id,
+				Sender:        sender,
+				Subscriptions: subscriptions,
 			}
 			if err := plugin.Boot(plugin.Bus); err != nil {
 				return err
@@ -64,3 +116,68 @@ func (r *GreekMilkBot) Run(ctx context.Context) error {
 	r.route.RunContext(ctx)
 	return nil
 }
+
+// 调用与元数据包属于控制流量，不应排在大量消息之后
+func packetPriority(_ RoutePacketHeader, packet models.Packet) Priority {
+	switch packet.Type {
+	case models.PacketTypeCall, models.PacketTypeMeta:
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
+// 将消息中插件不支持的内容降级，包可能被广播给多个插件，降级时复制消息
+func degradePacket(plugin *models.PluginInstance, packet models.Packet) (models.Packet, models.DegradeReport) {
+	capable, ok := plugin.Plugin.(models.ContentCapable)
+	if !ok {
+		return packet, models.DegradeReport{}
+	}
+	event, ok := packet.Data.(*models.PacketEvent)
+	if !ok {
+		return packet, models.DegradeReport{}
+	}
+	message, ok := event.Data.(*models.Message)
+	if !ok {
+		return packet, models.DegradeReport{}
+	}
+	degraded := *message
+	var report models.DegradeReport
+	degraded.Content, report = models.DefaultDegrader.Degrade(message.Content, models.SupportedSet(capable.SupportedContents()...))
+	if report.Empty() {
+		return packet, report
+	}
+	packet.Data = &models.PacketEvent{Type: event.Type, Data: &degraded}
+	return packet, report
+}
+
+// 将包分发到插件实现的接收器与类型订阅者
+func dispatchPacket(plugin *models.PluginInstance, bus models.PluginBus, packet models.Packet) {
+	if bus.Subscriptions != nil {
+		_, _ = bus.Subscriptions.Dispatch(bus, packet)
+	}
+	var event *models.Event
+	switch data := packet.Data.(type) {
+	case *models.PacketEvent:
+		switch item := data.Data.(type) {
+		case *models.Message:
+			if receiver, ok := plugin.Plugin.(models.MessageReceiver); ok {
+				_ = receiver.ReceiveMessage(bus, models.WithSrcPacket[models.Message]{Src: packet.Src, Data: *item})
+			}
+			return
+		case *models.Event:
+			event = item
+		case *models.EventUnknown:
+			if legacy, ok := item.Event(); ok {
+				event = &legacy
+			}
+		}
+	case *models.Event:
+		event = data
+	}
+	if event == nil {
+		return
+	}
+	if receiver, ok := plugin.Plugin.(models.EventReceiver); ok {
+		_ = receiver.ReceiveEvent(bus, models.WithSrcPacket[models.Event]{Src: packet.Src, Data: *event})
+	}
+}
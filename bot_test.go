@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/greek-milk-bot/core/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type typedBusPlugin struct {
+	boot     chan models.PluginBus
+	messages chan models.WithSrcPacket[models.Message]
+	requests chan models.WithSrcPacket[models.CallRequest]
+	events   chan models.WithSrcPacket[models.Event]
+}
+
+func (p *typedBusPlugin) Boot(bus models.PluginBus) error {
+	if p.messages != nil {
+		if err := models.Subscribe(bus, func(ctx context.Context, packet models.WithSrcPacket[models.Message]) {
+			p.messages <- packet
+		}); err != nil {
+			return err
+		}
+	}
+	if p.requests != nil {
+		if err := models.Subscribe(bus, func(ctx context.Context, packet models.WithSrcPacket[models.CallRequest]) {
+			p.requests <- packet
+		}); err != nil {
+			return err
+		}
+	}
+	if p.events != nil {
+		if err := models.Subscribe(bus, func(ctx context.Context, packet models.WithSrcPacket[models.Event]) {
+			p.events <- packet
+		}); err != nil {
+			return err
+		}
+	}
+	if p.boot != nil {
+		p.boot <- bus
+	}
+	return nil
+}
+
+// 测试类型订阅只接收匹配类型的包
+func TestTypedBus(t *testing.T) {
+	sender := &typedBusPlugin{boot: make(chan models.PluginBus, 1)}
+	receiver := &typedBusPlugin{
+		messages: make(chan models.WithSrcPacket[models.Message], 4),
+		requests: make(chan models.WithSrcPacket[models.CallRequest], 4),
+		events:   make(chan models.WithSrcPacket[models.Event], 4),
+	}
+	bot, err := NewGreekMilkBot(sender, receiver)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bot.Run(ctx)
+	}()
+
+	bus := <-sender.boot
+	assert.NoError(t, models.Publish(bus, "1", models.Message{ID: "m1"}))
+	assert.NoError(t, models.Publish(bus, "1", models.CallRequest{ID: "c1", Action: "ping"}))
+	// 没有订阅者的类型不会被投递到其他订阅
+	assert.NoError(t, models.Publish(bus, "1", models.PacketMeta{Action: "noop"}))
+	// 未解码的 JSON 按包类型解码后分发
+	assert.NoError(t, bus.SendPacket(models.Packet{
+		Dest: "1",
+		Type: models.PacketTypeEvent,
+		Data: json.RawMessage(`{"type":"poke","data":{"user":"u1"}}`),
+	}))
+
+	message := <-receiver.messages
+	assert.Equal(t, "0", message.Src)
+	assert.Equal(t, "m1", message.Data.ID)
+	request := <-receiver.requests
+	assert.Equal(t, "ping", request.Data.Action)
+	event := <-receiver.events
+	assert.Equal(t, "poke", event.Data.Type)
+	assert.Equal(t, "u1", event.Data.Data["user"])
+	assert.Empty(t, receiver.messages)
+}
+
+// 测试注册时检查类型
+func TestTypedBusUnsupported(t *testing.T) {
+	bus := models.PluginBus{Subscriptions: models.NewSubscriptions()}
+	err := models.Subscribe(bus, func(ctx context.Context, packet models.WithSrcPacket[string]) {})
+	assert.EqualError(t, err, "unsupported bus type string")
+	err = models.Subscribe(bus, func(ctx context.Context, packet models.WithSrcPacket[*models.Message]) {})
+	assert.Error(t, err)
+	assert.Error(t, models.Publish(bus, "", 42))
+
+	err = models.Subscribe(models.PluginBus{}, func(ctx context.Context, packet models.WithSrcPacket[models.Event]) {})
+	assert.EqualError(t, err, "plugin bus not connected")
+}
+
+type textOnlyPlugin struct {
+	typedBusPlugin
+}
+
+func (p *textOnlyPlugin) SupportedContents() []string {
+	return []string{"at"}
+}
+
+// 测试投递前按插件声明的能力降级内容
+func TestContentDegradation(t *testing.T) {
+	sender := &typedBusPlugin{boot: make(chan models.PluginBus, 1)}
+	rich := &typedBusPlugin{messages: make(chan models.WithSrcPacket[models.Message], 1)}
+	plain := &textOnlyPlugin{typedBusPlugin{messages: make(chan models.WithSrcPacket[models.Message], 1)}}
+	bot, err := NewGreekMilkBot(sender, rich, plain)
+	assert.NoError(t, err)
+	reports := make(chan models.DegradeReport, 1)
+	assert.NoError(t, bot.OnDegrade(func(plugin string, packet models.Packet, report models.DegradeReport) {
+		assert.Equal(t, "2", plugin)
+		reports <- report
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bot.Run(ctx)
+	}()
+
+	bus := <-sender.boot
+	content := models.NewContentsBuilder().At("u1").Location(1, 2, "").Build()
+	assert.NoError(t, models.Publish(bus, "", models.Message{ID: "m1", Content: content}))
+
+	// 支持所有类型的插件收到原始内容
+	assert.Equal(t, content, (<-rich.messages).Data.Content)
+	assert.Equal(t, models.Contents{
+		models.ContentAt{Uid: "u1"},
+		models.ContentText{Text: "1,2"},
+	}, (<-plain.messages).Data.Content)
+	assert.Equal(t, []models.DegradeEntry{{Index: 1, Path: []int{1}, From: "location", To: []string{"text"}}}, (<-reports).Entries)
+}
+
+type receiverPlugin struct {
+	boot     chan models.PluginBus
+	messages chan models.WithSrcPacket[models.Message]
+	events   chan models.WithSrcPacket[models.Event]
+}
+
+func newReceiverPlugin() *receiverPlugin {
+	return &receiverPlugin{
+		boot:     make(chan models.PluginBus, 1),
+		messages: make(chan models.WithSrcPacket[models.Message], 4),
+		events:   make(chan models.WithSrcPacket[models.Event], 4),
+	}
+}
+
+func (p *receiverPlugin) Boot(bus models.PluginBus) error {
+	p.boot <- bus
+	return nil
+}
+
+func (p *receiverPlugin) ReceiveMessage(bus models.PluginBus, msg models.WithSrcPacket[models.Message]) error {
+	p.messages <- msg
+	return nil
+}
+
+func (p *receiverPlugin) ReceiveEvent(bus models.PluginBus, msg models.WithSrcPacket[models.Event]) error {
+	p.events <- msg
+	return nil
+}
+
+// 测试插件总线的单播与广播分发
+func TestPluginBusDispatch(t *testing.T) {
+	a, b, c := newReceiverPlugin(), newReceiverPlugin(), newReceiverPlugin()
+	bot, err := NewGreekMilkBot(a, b, c)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bot.Run(ctx)
+	}()
+	bus := <-a.boot
+	<-b.boot
+	<-c.boot
+
+	// 单播消息只投递给目标插件
+	assert.NoError(t, bus.SendPacket(models.Packet{
+		Dest: "1",
+		Type: models.PacketTypeEvent,
+		Data: &models.PacketEvent{Type: models.EventTypeMessage, Data: &models.Message{ID: "m1"}},
+	}))
+	message := <-b.messages
+	assert.Equal(t, "0", message.Src)
+	assert.Equal(t, "m1", message.Data.ID)
+
+	// 广播事件投递给除发送者外的所有插件
+	assert.NoError(t, bus.SendPacket(models.Packet{
+		Type: models.PacketTypeEvent,
+		Data: &models.Event{Type: "poke"},
+	}))
+	assert.Equal(t, "poke", (<-b.events).Data.Type)
+	assert.Equal(t, "poke", (<-c.events).Data.Type)
+	assert.Empty(t, a.events)
+	assert.Empty(t, c.messages)
+}
+
+// 测试未连接的总线不能发送
+func TestPluginBusNotConnected(t *testing.T) {
+	assert.EqualError(t, models.PluginBus{}.SendPacket(models.Packet{}), "plugin bus not connected")
+}
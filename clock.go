@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时钟，路由中所有与时间相关的逻辑都通过它获取时间
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// Until 返回在 t 时刻触发的通道，t 已过去时立即触发
+	Until(t time.Time) <-chan time.Time
+}
+
+type systemClock struct{}
+
+// SystemClock 返回基于系统时间的时钟
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Until(t time.Time) <-chan time.Time {
+	return time.After(time.Until(t))
+}
+
+// ManualClock 手动推进的时钟，用于测试
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []manualWaiter
+}
+
+type manualWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewManualClock 创建从 start 开始的手动时钟
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) Until(t time.Time) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if !t.After(c.now) {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, manualWaiter{at: t, ch: ch})
+	return ch
+}
+
+// Advance 将时钟向前推进 d 并触发到期的等待者
+func (c *ManualClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set 将时钟设置到 t 并触发到期的等待者
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	remain := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.at.After(t) {
+			remain = append(remain, waiter)
+			continue
+		}
+		waiter.ch <- t
+	}
+	c.waiters = remain
+}
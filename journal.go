@@ -0,0 +1,389 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	journalSuffix = ".wal"
+
+	journalRecordPut byte = 1 // 写入记录
+	journalRecordAck byte = 2 // 确认记录
+
+	journalHeaderSize = 8     // 长度 + 校验和
+	journalBodyPrefix = 1 + 8 // 记录类型 + 序号
+)
+
+var journalCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// JournalOptions 日志配置
+type JournalOptions struct {
+	SegmentSize int64 // 单个分段的大小上限，超过后切换到新分段，0 表示使用默认值
+	SyncWrites  bool  // 每次写入后是否同步到磁盘
+}
+
+// DefaultJournalSegmentSize 默认分段大小
+const DefaultJournalSegmentSize = 16 << 20
+
+// JournalEntry 尚未确认的日志记录
+type JournalEntry struct {
+	Seq     uint64
+	Payload []byte
+}
+
+// Journal 仅追加的预写日志，按分段存储，每条记录带校验和
+//
+// 记录格式：4 字节长度 | 4 字节 CRC32-C | 1 字节类型 | 8 字节序号 | 数据
+type Journal struct {
+	dir  string
+	opts JournalOptions
+
+	mu       sync.Mutex
+	seq      uint64
+	segments []*journalSegment // 按编号升序，最后一个为当前写入分段
+	current  *os.File
+	live     map[uint64]*journalSegment // 未确认记录所在分段
+	payloads map[uint64][]byte          // 未确认记录的数据
+	err      error
+}
+
+type journalSegment struct {
+	id   uint64
+	path string
+	size int64
+	live int // 未确认的记录数量
+}
+
+// OpenJournal 打开目录中的日志，读取所有分段并恢复未确认的记录
+//
+// 分段末尾不完整或校验失败的记录视为崩溃时未写完的数据，会被截断
+func OpenJournal(dir string, opts JournalOptions) (*Journal, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultJournalSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		dir:      dir,
+		opts:     opts,
+		live:     make(map[uint64]*journalSegment),
+		payloads: make(map[uint64][]byte),
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, journalSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, journalSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		j.segments = append(j.segments, &journalSegment{id: id, path: filepath.Join(dir, name)})
+	}
+	sort.Slice(j.segments, func(a, b int) bool {
+		return j.segments[a].id < j.segments[b].id
+	})
+	for _, segment := range j.segments {
+		if err := j.load(segment); err != nil {
+			return nil, err
+		}
+	}
+	if len(j.segments) == 0 {
+		if err := j.rotate(); err != nil {
+			return nil, err
+		}
+	} else {
+		last := j.segments[len(j.segments)-1]
+		j.current, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// 读取分段中的记录
+func (j *Journal) load(segment *journalSegment) error {
+	file, err := os.Open(segment.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		kind, seq, payload, size, err := readJournalRecord(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				// 截断损坏的尾部
+				if err := os.Truncate(segment.path, offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		offset += size
+		j.seq = max(j.seq, seq)
+		switch kind {
+		case journalRecordPut:
+			if old, ok := j.live[seq]; ok {
+				// 压缩时被搬移过的记录
+				old.live--
+			}
+			j.live[seq] = segment
+			j.payloads[seq] = payload
+			segment.live++
+		case journalRecordAck:
+			if old, ok := j.live[seq]; ok {
+				old.live--
+				delete(j.live, seq)
+				delete(j.payloads, seq)
+			}
+		}
+	}
+	segment.size = offset
+	return nil
+}
+
+func readJournalRecord(reader io.Reader) (kind byte, seq uint64, payload []byte, size int64, err error) {
+	var header [journalHeaderSize]byte
+	if _, err = io.ReadFull(reader, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("journal: truncated record header")
+		}
+		return
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	checksum := binary.BigEndian.Uint32(header[4:8])
+	if length < journalBodyPrefix {
+		err = fmt.Errorf("journal: invalid record length %d", length)
+		return
+	}
+	body := make([]byte, length)
+	if _, err = io.ReadFull(reader, body); err != nil {
+		err = fmt.Errorf("journal: truncated record body: %w", err)
+		return
+	}
+	if crc32.Checksum(body, journalCRC) != checksum {
+		err = errors.New("journal: checksum mismatch")
+		return
+	}
+	kind = body[0]
+	seq = binary.BigEndian.Uint64(body[1:9])
+	payload = body[9:]
+	size = int64(journalHeaderSize) + int64(length)
+	return
+}
+
+func encodeJournalRecord(kind byte, seq uint64, payload []byte) []byte {
+	record := make([]byte, journalHeaderSize+journalBodyPrefix+len(payload))
+	body := record[journalHeaderSize:]
+	body[0] = kind
+	binary.BigEndian.PutUint64(body[1:9], seq)
+	copy(body[9:], payload)
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(body, journalCRC))
+	return record
+}
+
+// 切换到新的分段
+func (j *Journal) rotate() error {
+	var id uint64 = 1
+	if len(j.segments) > 0 {
+		id = j.segments[len(j.segments)-1].id + 1
+	}
+	path := filepath.Join(j.dir, fmt.Sprintf("%020d%s", id, journalSuffix))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if j.current != nil {
+		if err := j.current.Close(); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	j.current = file
+	j.segments = append(j.segments, &journalSegment{id: id, path: path})
+	return nil
+}
+
+func (j *Journal) write(kind byte, seq uint64, payload []byte) error {
+	if j.current == nil {
+		return errors.New("journal closed")
+	}
+	segment := j.segments[len(j.segments)-1]
+	record := encodeJournalRecord(kind, seq, payload)
+	if _, err := j.current.Write(record); err != nil {
+		return err
+	}
+	if j.opts.SyncWrites {
+		if err := j.current.Sync(); err != nil {
+			return err
+		}
+	}
+	segment.size += int64(len(record))
+	if kind == journalRecordPut {
+		if old, ok := j.live[seq]; ok {
+			old.live--
+		}
+		j.live[seq] = segment
+		j.payloads[seq] = payload
+		segment.live++
+	}
+	return nil
+}
+
+// 写入后分段超过上限时切换分段并压缩
+func (j *Journal) maybeRotate() error {
+	if j.segments[len(j.segments)-1].size < j.opts.SegmentSize {
+		return nil
+	}
+	if err := j.rotate(); err != nil {
+		return err
+	}
+	return j.compact()
+}
+
+// Append 写入一条记录，返回其序号
+//
+// 记录写入后切换分段或压缩失败不影响本次写入，错误通过 Err 获取
+func (j *Journal) Append(payload []byte) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	seq := j.seq + 1
+	if err := j.write(journalRecordPut, seq, append([]byte(nil), payload...)); err != nil {
+		return 0, j.fail(err)
+	}
+	j.seq = seq
+	_ = j.fail(j.maybeRotate())
+	return seq, nil
+}
+
+// Ack 确认一条记录，确认后的记录不会再被恢复
+//
+// 与 Append 相同，确认记录写入后切换分段或压缩失败的错误通过 Err 获取
+func (j *Journal) Ack(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	segment, ok := j.live[seq]
+	if !ok {
+		return nil
+	}
+	if err := j.write(journalRecordAck, seq, nil); err != nil {
+		return j.fail(err)
+	}
+	segment.live--
+	delete(j.live, seq)
+	delete(j.payloads, seq)
+	_ = j.fail(j.maybeRotate())
+	return nil
+}
+
+// Pending 返回所有未确认的记录，按序号升序
+func (j *Journal) Pending() []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	result := make([]JournalEntry, 0, len(j.payloads))
+	for seq, payload := range j.payloads {
+		result = append(result, JournalEntry{Seq: seq, Payload: payload})
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].Seq < result[b].Seq
+	})
+	return result
+}
+
+// Compact 压缩日志：删除只包含已确认记录的旧分段，
+// 旧分段中未确认的记录搬移到当前分段后删除旧分段
+func (j *Journal) Compact() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.fail(j.compact())
+}
+
+func (j *Journal) compact() error {
+	if len(j.segments) < 2 {
+		return nil
+	}
+	old := j.segments[:len(j.segments)-1]
+	var moving []uint64
+	for seq, segment := range j.live {
+		for _, item := range old {
+			if item == segment {
+				moving = append(moving, seq)
+				break
+			}
+		}
+	}
+	sort.Slice(moving, func(a, b int) bool {
+		return moving[a] < moving[b]
+	})
+	for _, seq := range moving {
+		if err := j.write(journalRecordPut, seq, j.payloads[seq]); err != nil {
+			return err
+		}
+	}
+	if len(moving) > 0 && !j.opts.SyncWrites {
+		// 删除旧分段前确保搬移的记录已落盘
+		if err := j.current.Sync(); err != nil {
+			return err
+		}
+	}
+	for _, segment := range old {
+		if err := os.Remove(segment.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	j.segments = append([]*journalSegment(nil), j.segments[len(j.segments)-1])
+	return nil
+}
+
+// Segments 返回当前分段数量
+func (j *Journal) Segments() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.segments)
+}
+
+// Err 返回第一次写入、切换分段或压缩失败的错误
+func (j *Journal) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+func (j *Journal) fail(err error) error {
+	if err != nil && j.err == nil {
+		j.err = err
+	}
+	return err
+}
+
+// Close 关闭日志
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.current == nil {
+		return nil
+	}
+	err := j.current.Close()
+	j.current = nil
+	return err
+}
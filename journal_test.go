@@ -0,0 +1,305 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试日志写入、确认与重新打开后恢复
+func TestJournalReopen(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+
+	seq1, err := journal.Append([]byte("one"))
+	assert.NoError(t, err)
+	seq2, err := journal.Append([]byte("two"))
+	assert.NoError(t, err)
+	seq3, err := journal.Append([]byte("three"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, []uint64{seq1, seq2, seq3})
+	assert.NoError(t, journal.Ack(seq2))
+	assert.NoError(t, journal.Close())
+
+	journal, err = OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.Equal(t, []JournalEntry{
+		{Seq: 1, Payload: []byte("one")},
+		{Seq: 3, Payload: []byte("three")},
+	}, journal.Pending())
+
+	// 序号在重新打开后继续递增
+	seq4, err := journal.Append([]byte("four"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), seq4)
+}
+
+// 测试截断崩溃时写了一半的记录
+func TestJournalTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	_, err = journal.Append([]byte("complete"))
+	assert.NoError(t, err)
+	_, err = journal.Append([]byte("torn"))
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.wal"))
+	assert.Len(t, files, 1)
+	info, err := os.Stat(files[0])
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(files[0], info.Size()-2))
+
+	journal, err = OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []JournalEntry{{Seq: 1, Payload: []byte("complete")}}, journal.Pending())
+	// 截断后可以继续追加
+	_, err = journal.Append([]byte("next"))
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Close())
+
+	journal, err = OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.Len(t, journal.Pending(), 2)
+}
+
+// 测试校验和不匹配的记录被丢弃
+func TestJournalChecksum(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	_, err = journal.Append([]byte("payload"))
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.wal"))
+	data, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	assert.NoError(t, os.WriteFile(files[0], data, 0o644))
+
+	journal, err = OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.Empty(t, journal.Pending())
+}
+
+// 测试分段切换与压缩
+func TestJournalRotateAndCompact(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir, JournalOptions{SegmentSize: 64})
+	assert.NoError(t, err)
+
+	var seqs []uint64
+	for i := 0; i < 10; i++ {
+		seq, err := journal.Append([]byte("0123456789abcdef"))
+		assert.NoError(t, err)
+		seqs = append(seqs, seq)
+	}
+	// 只保留最后一条未确认
+	for _, seq := range seqs[:9] {
+		assert.NoError(t, journal.Ack(seq))
+	}
+	assert.NoError(t, journal.Compact())
+	assert.Equal(t, 1, journal.Segments())
+	files, _ := filepath.Glob(filepath.Join(dir, "*.wal"))
+	assert.Len(t, files, 1)
+	assert.NoError(t, journal.Close())
+
+	journal, err = OpenJournal(dir, JournalOptions{SegmentSize: 64})
+	assert.NoError(t, err)
+	defer journal.Close()
+	pending := journal.Pending()
+	assert.Len(t, pending, 1)
+	assert.Equal(t, seqs[9], pending[0].Seq)
+}
+
+// 测试切换分段失败时已写入的记录仍然有效
+func TestJournalRotateError(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir, JournalOptions{SegmentSize: 16})
+	assert.NoError(t, err)
+	// 占用下一个分段的路径使切换分段失败
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, fmt.Sprintf("%020d%s", 2, journalSuffix)), 0o755))
+
+	seq, err := journal.Append([]byte("0123456789abcdef"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), seq)
+	assert.Error(t, journal.Err())
+	assert.Equal(t, []JournalEntry{{Seq: 1, Payload: []byte("0123456789abcdef")}}, journal.Pending())
+	assert.NoError(t, journal.Ack(seq))
+	assert.Empty(t, journal.Pending())
+	assert.NoError(t, journal.Close())
+
+	journal, err = OpenJournal(dir, JournalOptions{SegmentSize: 16})
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.Empty(t, journal.Pending())
+}
+
+// 测试路由器重启后重新投递未确认的持久包
+func TestRouterJournalReplay(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+
+	// 第一次运行：包进入队列后进程退出，未被处理
+	router := NewRouter[string](64, WithJournal[string](journal, nil))
+	sender, _ := router.AddRoute("sender")
+	_, _ = router.AddRoute("billing")
+	sender.SendDurable("billing", "invoice-1")
+	sender.SendDurable("billing", "invoice-2")
+	sender.Send("billing", "not durable")
+	router.Stop()
+	assert.NoError(t, journal.Close())
+
+	// 第二次运行：未确认的持久包被重新投递并确认
+	journal, err = OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	router = NewRouter[string](64, WithJournal[string](journal, nil))
+	_, _ = router.AddRoute("sender")
+	billing, _ := router.AddRoute("billing")
+	var mu sync.Mutex
+	var received []string
+	billing.ReliableHandlerFunc(func(header RoutePacketHeader, data string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.True(t, header.Durable)
+		received = append(received, data)
+		return nil
+	})
+	go router.Run()
+	assert.Eventually(t, func() bool {
+		return len(journal.Pending()) == 0
+	}, time.Second, 10*time.Millisecond)
+	router.Stop()
+	assert.NoError(t, journal.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"invoice-1", "invoice-2"}, received)
+}
+
+// 测试持久组播在所有成员确认后才从日志中移除
+func TestRouterJournalMulticastAck(t *testing.T) {
+	journal, err := OpenJournal(t.TempDir(), JournalOptions{})
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	router := NewRouter[string](64, WithJournal[string](journal, nil))
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	fast, _ := router.AddRoute("fast")
+	slow, _ := router.AddRoute("slow")
+	assert.NoError(t, fast.JoinGroup("group"))
+	assert.NoError(t, slow.JoinGroup("group"))
+	fast.HandlerFunc(func(header RoutePacketHeader, data string) {})
+	release := make(chan struct{})
+	slow.HandlerFunc(func(header RoutePacketHeader, data string) {
+		<-release
+	})
+
+	sender.SendGroupDurable("group", "event")
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, journal.Pending(), 1)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return len(journal.Pending()) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// 测试写入日志失败的持久包进入死信
+func TestRouterJournalAppendError(t *testing.T) {
+	journal, err := OpenJournal(t.TempDir(), JournalOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Close())
+
+	var letters []DeadLetter[string]
+	router := NewRouter[string](64,
+		WithJournal[string](journal, nil),
+		WithSynchronousDelivery[string](),
+		WithDeadLetter(func(letter DeadLetter[string]) {
+			letters = append(letters, letter)
+		}))
+	sender, _ := router.AddRoute("sender")
+	billing, _ := router.AddRoute("billing")
+	var received []string
+	billing.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received = append(received, data)
+	})
+	sender.SendDurable("billing", "invoice")
+	router.Flush()
+
+	assert.Empty(t, received)
+	assert.Len(t, letters, 1)
+	assert.Equal(t, DropReasonJournal, letters[0].Reason)
+	assert.EqualError(t, letters[0].Err, "journal closed")
+}
+
+// 测试同步投递模式下重新投递超过队列容量的日志记录
+func TestRouterJournalReplaySynchronous(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	router := NewRouter[string](8, WithJournal[string](journal, nil), WithSynchronousDelivery[string]())
+	sender, _ := router.AddRoute("sender")
+	for i := range 20 {
+		sender.SendDurable("billing", fmt.Sprint(i))
+	}
+	assert.NoError(t, journal.Close())
+
+	journal, err = OpenJournal(dir, JournalOptions{})
+	assert.NoError(t, err)
+	defer journal.Close()
+	router = NewRouter[string](8, WithJournal[string](journal, nil), WithSynchronousDelivery[string]())
+	billing, _ := router.AddRoute("billing")
+	var received []string
+	billing.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received = append(received, data)
+	})
+	assert.Equal(t, 20, router.Flush())
+	assert.Len(t, received, 20)
+	assert.Empty(t, journal.Pending())
+}
+
+// 测试确认日志失败时通过死信报告
+func TestRouterJournalAckError(t *testing.T) {
+	journal, err := OpenJournal(t.TempDir(), JournalOptions{})
+	assert.NoError(t, err)
+
+	var letters []DeadLetter[string]
+	router := NewRouter[string](64,
+		WithJournal[string](journal, nil),
+		WithSynchronousDelivery[string](),
+		WithDeadLetter(func(letter DeadLetter[string]) {
+			letters = append(letters, letter)
+		}))
+	sender, _ := router.AddRoute("sender")
+	billing, _ := router.AddRoute("billing")
+	var received []string
+	billing.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received = append(received, data)
+	})
+	sender.SendDurable("billing", "invoice")
+	// 包已写入日志，投递前关闭日志使确认失败
+	assert.NoError(t, journal.Close())
+	router.Flush()
+
+	assert.Equal(t, []string{"invoice"}, received)
+	assert.Len(t, letters, 1)
+	assert.Equal(t, DropReasonJournalAck, letters[0].Reason)
+	assert.Equal(t, "billing", letters[0].Route)
+	assert.EqualError(t, letters[0].Err, "journal closed")
+}
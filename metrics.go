@@ -0,0 +1,263 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 丢弃原因
+const (
+	DropReasonNoRoute     = "no_route"     // 目标路由不存在或没有处理器
+	DropReasonNoGroup     = "no_group"     // 目标组不存在或没有可投递成员
+	DropReasonNoPending   = "no_pending"   // 应答没有对应的请求
+	DropReasonStopped     = "stopped"      // 路由器已停止
+	DropReasonLoop        = "loop"         // 转发形成环路
+	DropReasonStackDepth  = "stack_depth"  // 转发栈超过最大深度
+	DropReasonDuplicate   = "duplicate"    // 重复包
+	DropReasonUnacked     = "unacked"      // 可靠包重试后仍未被确认
+	DropReasonRateLimited = "rate_limited" // 超出限流
+	DropReasonJournal     = "journal"      // 持久包写入日志失败
+	DropReasonJournalAck  = "journal_ack"  // 持久包确认日志失败，重启后会再次投递
+)
+
+// MetricsSink 路由指标接收器，实现需要保证并发安全
+type MetricsSink interface {
+	// PacketSent 包进入队列
+	PacketSent(packetType RoutePacketType, src string)
+	// PacketDelivered 包被处理器处理完成
+	PacketDelivered(packetType RoutePacketType, route string)
+	// PacketFiltered 包被过滤器拦截
+	PacketFiltered(packetType RoutePacketType, dest string)
+	// PacketExpired 包因 TTL 耗尽被丢弃
+	PacketExpired(packetType RoutePacketType, dest string)
+	// PacketDropped 包因其他原因被丢弃
+	PacketDropped(packetType RoutePacketType, dest string, reason string)
+	// QueueDepth 队列中等待处理的包数量
+	QueueDepth(depth int)
+	// HandlerLatency 处理器耗时
+	HandlerLatency(route string, latency time.Duration)
+}
+
+// WithMetrics 设置路由器的指标接收器
+func WithMetrics[T any](sink MetricsSink) RouterOption[T] {
+	return func(r *Router[T]) {
+		r.metrics = sink
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) PacketSent(RoutePacketType, string)            {}
+func (noopMetrics) PacketDelivered(RoutePacketType, string)       {}
+func (noopMetrics) PacketFiltered(RoutePacketType, string)        {}
+func (noopMetrics) PacketExpired(RoutePacketType, string)         {}
+func (noopMetrics) PacketDropped(RoutePacketType, string, string) {}
+func (noopMetrics) QueueDepth(int)                                {}
+func (noopMetrics) HandlerLatency(string, time.Duration)          {}
+
+// DefaultLatencyBuckets 默认的处理器耗时分桶（秒）
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+// PrometheusMetrics 内存中的指标统计，并以 Prometheus 文本格式对外暴露
+type PrometheusMetrics struct {
+	namespace string
+	buckets   []float64
+
+	mu         sync.Mutex
+	counters   map[string]map[string]uint64 // 指标名 - 标签 - 计数
+	queueDepth int
+	latency    map[string]*histogram // 路由 - 耗时分布
+}
+
+type histogram struct {
+	counts []uint64 // 与 buckets 对应，非累计
+	count  uint64
+	sum    float64
+}
+
+// NewPrometheusMetrics 创建指标统计，namespace 作为指标名前缀，buckets 为空时使用默认分桶
+func NewPrometheusMetrics(namespace string, buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetrics{
+		namespace: namespace,
+		buckets:   buckets,
+		counters:  make(map[string]map[string]uint64),
+		latency:   make(map[string]*histogram),
+	}
+}
+
+const (
+	metricSent      = "router_packets_sent_total"
+	metricDelivered = "router_packets_delivered_total"
+	metricFiltered  = "router_packets_filtered_total"
+	metricExpired   = "router_packets_expired_total"
+	metricDropped   = "router_packets_dropped_total"
+	metricQueue     = "router_queue_depth"
+	metricLatency   = "router_handler_latency_seconds"
+)
+
+var metricHelp = map[string]string{
+	metricSent:      "Packets put into the router queue.",
+	metricDelivered: "Packets handled by route handlers.",
+	metricFiltered:  "Packets blocked by filters.",
+	metricExpired:   "Packets dropped because the TTL ran out.",
+	metricDropped:   "Packets dropped for other reasons.",
+	metricQueue:     "Packets waiting in the router queue.",
+	metricLatency:   "Route handler latency in seconds.",
+}
+
+func (m *PrometheusMetrics) inc(name string, labels ...string) {
+	var b strings.Builder
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(labels[i+1]))
+		b.WriteByte('"')
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	series, ok := m.counters[name]
+	if !ok {
+		series = make(map[string]uint64)
+		m.counters[name] = series
+	}
+	series[b.String()]++
+}
+
+func (m *PrometheusMetrics) PacketSent(packetType RoutePacketType, src string) {
+	m.inc(metricSent, "type", packetType.String(), "src", src)
+}
+
+func (m *PrometheusMetrics) PacketDelivered(packetType RoutePacketType, route string) {
+	m.inc(metricDelivered, "type", packetType.String(), "route", route)
+}
+
+func (m *PrometheusMetrics) PacketFiltered(packetType RoutePacketType, dest string) {
+	m.inc(metricFiltered, "type", packetType.String(), "dest", dest)
+}
+
+func (m *PrometheusMetrics) PacketExpired(packetType RoutePacketType, dest string) {
+	m.inc(metricExpired, "type", packetType.String(), "dest", dest)
+}
+
+func (m *PrometheusMetrics) PacketDropped(packetType RoutePacketType, dest string, reason string) {
+	m.inc(metricDropped, "type", packetType.String(), "dest", dest, "reason", reason)
+}
+
+func (m *PrometheusMetrics) QueueDepth(depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queueDepth = depth
+}
+
+func (m *PrometheusMetrics) HandlerLatency(route string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.latency[route]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latency[route] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ServeHTTP 以 Prometheus 文本格式输出所有指标
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = m.WriteTo(w)
+}
+
+// WriteTo 以 Prometheus 文本格式写出所有指标
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	for _, name := range []string{metricSent, metricDelivered, metricFiltered, metricExpired, metricDropped} {
+		series := m.counters[name]
+		if len(series) == 0 {
+			continue
+		}
+		m.writeHeader(&b, name, "counter")
+		labels := make([]string, 0, len(series))
+		for label := range series {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			fmt.Fprintf(&b, "%s{%s} %d\n", m.name(name), label, series[label])
+		}
+	}
+
+	m.writeHeader(&b, metricQueue, "gauge")
+	fmt.Fprintf(&b, "%s %d\n", m.name(metricQueue), m.queueDepth)
+
+	if len(m.latency) > 0 {
+		m.writeHeader(&b, metricLatency, "histogram")
+		routes := make([]string, 0, len(m.latency))
+		for route := range m.latency {
+			routes = append(routes, route)
+		}
+		sort.Strings(routes)
+		for _, route := range routes {
+			h := m.latency[route]
+			label := `route="` + escapeLabel(route) + `"`
+			var cumulative uint64
+			for i, bound := range m.buckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", m.name(metricLatency), label, formatFloat(bound), cumulative)
+			}
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", m.name(metricLatency), label, h.count)
+			fmt.Fprintf(&b, "%s_sum{%s} %s\n", m.name(metricLatency), label, formatFloat(h.sum))
+			fmt.Fprintf(&b, "%s_count{%s} %d\n", m.name(metricLatency), label, h.count)
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (m *PrometheusMetrics) name(metric string) string {
+	if m.namespace == "" {
+		return metric
+	}
+	return m.namespace + "_" + metric
+}
+
+func (m *PrometheusMetrics) writeHeader(b *strings.Builder, metric, kind string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", m.name(metric), metricHelp[metric])
+	fmt.Fprintf(b, "# TYPE %s %s\n", m.name(metric), kind)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试路由器指标统计与 Prometheus 输出
+func TestPrometheusMetrics(t *testing.T) {
+	metrics := NewPrometheusMetrics("bot")
+	router := NewRouter[string](64, WithMetrics[string](metrics))
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	receiver, _ := router.AddRoute("receiver")
+
+	blockFilter := Filter[string](func(header RoutePacketHeader, data string) bool {
+		return data == "blocked"
+	})
+	assert.NoError(t, receiver.AddFilter("receiver", &blockFilter))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	receiver.HandlerFunc(func(header RoutePacketHeader, data string) {
+		defer wg.Done()
+	})
+
+	sender.Send("receiver", "hello")
+	sender.Send("receiver", "blocked")
+	sender.Send("nobody", "lost")
+	sender.Send("receiver", "world")
+	wg.Wait()
+	time.Sleep(50 * time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	text := string(body)
+
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, text, "# TYPE bot_router_packets_sent_total counter\n")
+	assert.Contains(t, text, `bot_router_packets_sent_total{type="unicast",src="sender"} 4`)
+	assert.Contains(t, text, `bot_router_packets_delivered_total{type="unicast",route="receiver"} 2`)
+	assert.Contains(t, text, `bot_router_packets_filtered_total{type="unicast",dest="receiver"} 1`)
+	assert.Contains(t, text, `bot_router_packets_dropped_total{type="unicast",dest="nobody",reason="no_route"} 1`)
+	assert.Contains(t, text, "bot_router_queue_depth 0\n")
+	assert.Contains(t, text, `bot_router_handler_latency_seconds_bucket{route="receiver",le="+Inf"} 2`)
+	assert.Contains(t, text, `bot_router_handler_latency_seconds_count{route="receiver"} 2`)
+}
+
+// 测试 TTL 耗尽计数与标签转义
+func TestPrometheusMetricsExpired(t *testing.T) {
+	metrics := NewPrometheusMetrics("", 0.5, 0.1)
+	router := NewRouter[string](1, WithMetrics[string](metrics))
+
+	route, _ := router.AddRoute("a\"b")
+	route.SendForward("c", &RoutePacketHeader{Type: RoutePacketTypeUnicast, Ttl: 1}, "x")
+	metrics.HandlerLatency("a\"b", 200*time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	text := recorder.Body.String()
+	assert.Contains(t, text, `router_packets_expired_total{type="unicast",dest="c"} 1`)
+	assert.Contains(t, text, `router_handler_latency_seconds_bucket{route="a\"b",le="0.1"} 0`)
+	assert.Contains(t, text, `router_handler_latency_seconds_bucket{route="a\"b",le="0.5"} 1`)
+	assert.Contains(t, text, `router_handler_latency_seconds_sum{route="a\"b"} 0.2`)
+}
@@ -2,13 +2,30 @@ package models
 
 import (
 	"context"
+	"errors"
 )
 
+// PacketSender 将包发送到总线，ctx 携带链路等上下文信息
+type PacketSender func(ctx context.Context, packet Packet) error
+
 type PluginBus struct {
 	context.Context
-	ID string
+	ID            string
+	Sender        PacketSender
+	Subscriptions *Subscriptions // 类型订阅表，见 Subscribe
 }
 
+// SendPacket 发送包，Dest 为空时广播给其他所有插件
 func (bus PluginBus) SendPacket(packet Packet) error {
-	panic("implement me")
+	if bus.Sender == nil {
+		return errors.New("plugin bus not connected")
+	}
+	packet.Src = bus.ID
+	return bus.Sender(bus.Context, packet)
+}
+
+// WithContext 返回使用 ctx 的总线副本
+func (bus PluginBus) WithContext(ctx context.Context) PluginBus {
+	bus.Context = ctx
+	return bus
 }
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// 可在总线上传输的数据类型及其包装方式
+var packetKinds = map[reflect.Type]func(data any) Packet{
+	reflect.TypeFor[CallRequest](): func(data any) Packet {
+		request := data.(CallRequest)
+		return Packet{Type: PacketTypeCall, Data: &PacketCall{Type: CallTypeRequest, Data: &request}}
+	},
+	reflect.TypeFor[CallResponse](): func(data any) Packet {
+		response := data.(CallResponse)
+		return Packet{Type: PacketTypeCall, Data: &PacketCall{Type: CallTypeResponse, Data: &response}}
+	},
+	reflect.TypeFor[PacketMeta](): func(data any) Packet {
+		meta := data.(PacketMeta)
+		return Packet{Type: PacketTypeMeta, Data: &meta}
+	},
+	reflect.TypeFor[EventUnknown](): func(data any) Packet {
+		event := data.(EventUnknown)
+		return Packet{Type: PacketTypeEvent, Data: &PacketEvent{Type: event.Type, Data: &event}}
+	},
+}
+
+// 返回类型的包装方式，注册的事件类型包装为事件包
+func lookupPacketKind(typeOf reflect.Type) (func(data any) Packet, bool) {
+	if wrap, ok := packetKinds[typeOf]; ok {
+		return wrap, true
+	}
+	key, ok := eventKey(typeOf)
+	if !ok {
+		return nil, false
+	}
+	return func(data any) Packet {
+		value := reflect.New(typeOf)
+		value.Elem().Set(reflect.ValueOf(data))
+		return Packet{Type: PacketTypeEvent, Data: &PacketEvent{Type: key, Data: value.Interface()}}
+	}, true
+}
+
+// 检查 T 是否可以在总线上传输
+func packetKind[T any]() (reflect.Type, func(data any) Packet, error) {
+	typeOf := reflect.TypeFor[T]()
+	wrap, ok := lookupPacketKind(typeOf)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported bus type %s", typeOf)
+	}
+	return typeOf, wrap, nil
+}
+
+// Subscriptions 插件的类型订阅表
+type Subscriptions struct {
+	mu       sync.RWMutex
+	handlers map[reflect.Type][]func(ctx context.Context, src string, data any)
+}
+
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{
+		handlers: make(map[reflect.Type][]func(ctx context.Context, src string, data any)),
+	}
+}
+
+// Dispatch 解码包并调用类型匹配的订阅者，返回被调用的订阅者数量
+func (s *Subscriptions) Dispatch(ctx context.Context, packet Packet) (int, error) {
+	data, err := UnwrapPacket(packet)
+	if err != nil {
+		return 0, err
+	}
+	count := s.dispatch(ctx, packet.Src, data)
+	// 未知事件同时以旧格式分发给 Event 的订阅者
+	if unknown, ok := data.(EventUnknown); ok {
+		if event, ok := unknown.Event(); ok {
+			count += s.dispatch(ctx, packet.Src, event)
+		}
+	}
+	return count, nil
+}
+
+func (s *Subscriptions) dispatch(ctx context.Context, src string, data any) int {
+	s.mu.RLock()
+	handlers := s.handlers[reflect.TypeOf(data)]
+	s.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, src, data)
+	}
+	return len(handlers)
+}
+
+// Subscribe 订阅类型为 T 的包，T 不能在总线上传输时返回错误
+func Subscribe[T any](bus PluginBus, handler func(ctx context.Context, packet WithSrcPacket[T])) error {
+	if bus.Subscriptions == nil {
+		return errors.New("plugin bus not connected")
+	}
+	typeOf, _, err := packetKind[T]()
+	if err != nil {
+		return err
+	}
+	bus.Subscriptions.mu.Lock()
+	defer bus.Subscriptions.mu.Unlock()
+	bus.Subscriptions.handlers[typeOf] = append(bus.Subscriptions.handlers[typeOf],
+		func(ctx context.Context, src string, data any) {
+			handler(ctx, WithSrcPacket[T]{Src: src, Data: data.(T)})
+		})
+	return nil
+}
+
+// Publish 将 data 包装为对应类型的包发送，dest 为空时广播
+func Publish[T any](bus PluginBus, dest string, data T) error {
+	_, wrap, err := packetKind[T]()
+	if err != nil {
+		return err
+	}
+	packet := wrap(data)
+	packet.Dest = dest
+	return bus.SendPacket(packet)
+}
+
+// UnwrapPacket 取出包中的数据，返回值为注册的事件类型、EventUnknown、CallRequest、CallResponse 或 PacketMeta
+//
+// 数据为未解码的 JSON（json.RawMessage 或 []byte）时按包类型解码
+func UnwrapPacket(packet Packet) (any, error) {
+	var raw json.RawMessage
+	switch data := packet.Data.(type) {
+	case json.RawMessage:
+		raw = data
+	case []byte:
+		raw = data
+	}
+	if raw != nil {
+		encoded, err := json.Marshal(jsonPacket{Type: packet.Type, Data: raw})
+		if err != nil {
+			return nil, err
+		}
+		var decoded Packet
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			return nil, err
+		}
+		packet.Data = decoded.Data
+	}
+	switch data := packet.Data.(type) {
+	case *PacketEvent:
+		return unwrapValue(data.Data)
+	case PacketEvent:
+		return unwrapValue(data.Data)
+	case *PacketCall:
+		return unwrapValue(data.Data)
+	case PacketCall:
+		return unwrapValue(data.Data)
+	}
+	return unwrapValue(packet.Data)
+}
+
+// 解引用指针并检查类型
+func unwrapValue(data any) (any, error) {
+	value := reflect.ValueOf(data)
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, errors.New("nil packet data")
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil, errors.New("empty packet data")
+	}
+	if _, ok := lookupPacketKind(value.Type()); !ok {
+		return nil, fmt.Errorf("unsupported packet data %T", data)
+	}
+	return value.Interface(), nil
+}
@@ -4,12 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
-)
-
-var (
-	covertMap  = make(map[string]reflect.Type)
-	covertMapR = make(map[reflect.Type]string)
+	"sync"
 )
 
 type (
@@ -18,62 +15,16 @@ type (
 )
 
 func (contents *Contents) UnmarshalJSON(bytes []byte) error {
-	var raw RAWContents
-	err := json.Unmarshal(bytes, &raw)
+	result, err := DefaultContentRegistry.Decode(bytes)
 	if err != nil {
 		return err
 	}
-	result := make(Contents, 0, len(raw))
-	for _, content := range raw {
-		r := covertMap[content.Type]
-		if r == nil {
-			result = append(result,
-				ContentUnknown{
-					Type:  content.Type,
-					Value: content.Data,
-				})
-			continue
-		}
-		i := reflect.New(r).Interface()
-		if err := json.Unmarshal([]byte(content.Data), i); err != nil {
-			return err
-		}
-		result = append(result, reflect.ValueOf(i).Elem().Interface().(Content))
-	}
 	*contents = result
 	return nil
 }
 
 func (contents *Contents) MarshalJSON() ([]byte, error) {
-	result := make(RAWContents, 0, len(*contents))
-	for _, content := range *contents {
-		t := reflect.TypeOf(content)
-		if t.Kind() == reflect.Ptr {
-			t = t.Elem()
-		}
-		s := covertMapR[t]
-		if s == "" {
-			switch typedContent := content.(type) {
-			case ContentUnknown:
-				result = append(result, RawContent{
-					Type: typedContent.Type,
-					Data: typedContent.Value,
-				})
-				continue
-			default:
-				return nil, fmt.Errorf("unknown type %T", typedContent)
-			}
-		}
-		data, err := json.Marshal(content)
-		if err != nil {
-			return nil, err
-		}
-		result = append(result, RawContent{
-			Type: s,
-			Data: string(data),
-		})
-	}
-	return json.Marshal(result)
+	return DefaultContentRegistry.Encode(*contents)
 }
 
 func (contents *Contents) String() string {
@@ -95,18 +46,258 @@ type RawContent struct {
 
 var baseType = reflect.TypeOf((*Content)(nil)).Elem()
 
+// ContentRegistry 内容类型注册表，保存类型键与 Go 类型的对应关系，并发安全
+//
+// 类型键由小写字母、数字、_ 与 - 组成，可以用 . 分隔命名空间，例如 qq.face
+type ContentRegistry struct {
+	mu      sync.RWMutex
+	types   map[string]reflect.Type
+	keys    map[reflect.Type]string
+	markups map[string]MarkupShorthand // 标记语法的简写形式
+}
+
+func NewContentRegistry() *ContentRegistry {
+	return &ContentRegistry{
+		types:   make(map[string]reflect.Type),
+		keys:    make(map[reflect.Type]string),
+		markups: make(map[string]MarkupShorthand),
+	}
+}
+
+// DefaultContentRegistry 默认注册表，Contents 的 JSON 编解码使用该注册表
+var DefaultContentRegistry = NewContentRegistry()
+
+// RegisterContent 在默认注册表中注册内容类型，注册失败时 panic
 func RegisterContent(key string, typeOf reflect.Type) {
-	if _, ok := covertMap[key]; ok {
-		panic("duplicate key " + key)
+	if err := DefaultContentRegistry.Register(key, typeOf); err != nil {
+		panic(err)
+	}
+}
+
+// 检查类型键格式
+func validContentKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, segment := range strings.Split(key, ".") {
+		if segment == "" {
+			return false
+		}
+		for _, c := range segment {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Register 注册内容类型，typeOf 可以是值类型或指针类型
+func (r *ContentRegistry) Register(key string, typeOf reflect.Type) error {
+	if !validContentKey(key) {
+		return fmt.Errorf("invalid content key %q", key)
 	}
-	if !typeOf.Implements(baseType) {
-		panic("type" + key + " not implements Content")
+	if typeOf == nil || !typeOf.Implements(baseType) {
+		return fmt.Errorf("type %s of %s not implements Content", typeOf, key)
 	}
 	if typeOf.Kind() == reflect.Ptr {
 		typeOf = typeOf.Elem()
 	}
-	covertMap[key] = typeOf
-	covertMapR[typeOf] = key
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[key]; ok {
+		return fmt.Errorf("duplicate key %s", key)
+	}
+	if old, ok := r.keys[typeOf]; ok {
+		return fmt.Errorf("type %s already registered as %s", typeOf, old)
+	}
+	r.types[key] = typeOf
+	r.keys[typeOf] = key
+	return nil
+}
+
+// Lookup 返回类型键对应的 Go 类型
+func (r *ContentRegistry) Lookup(key string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	typeOf, ok := r.types[key]
+	return typeOf, ok
+}
+
+// KeyOf 返回内容的类型键
+func (r *ContentRegistry) KeyOf(content Content) (string, bool) {
+	typeOf := reflect.TypeOf(content)
+	if typeOf == nil {
+		return "", false
+	}
+	if typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[typeOf]
+	return key, ok
+}
+
+// Keys 返回所有类型键，按字典序排列
+func (r *ContentRegistry) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]string, 0, len(r.types))
+	for key := range r.types {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Clone 复制注册表与其中的简写形式，可在副本上注册额外的类型而不影响原注册表
+func (r *ContentRegistry) Clone() *ContentRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clone := NewContentRegistry()
+	for key, typeOf := range r.types {
+		clone.types[key] = typeOf
+		clone.keys[typeOf] = key
+	}
+	for key, shorthand := range r.markups {
+		clone.markups[key] = shorthand
+	}
+	return clone
+}
+
+// DecodeContent 按类型键解码单个内容，未注册的类型保留为 ContentUnknown
+func (r *ContentRegistry) DecodeContent(key string, data []byte) (Content, error) {
+	typeOf, ok := r.Lookup(key)
+	if !ok {
+		return ContentUnknown{Type: key, Value: string(data)}, nil
+	}
+	value := reflect.New(typeOf)
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface().(Content), nil
+}
+
+// Decode 解码 JSON 格式的消息内容
+//
+// 合并转发中嵌套的消息先按默认注册表解码，其中未知的类型再按本注册表解析
+func (r *ContentRegistry) Decode(data []byte) (Contents, error) {
+	var raw RAWContents
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	result := make(Contents, 0, len(raw))
+	for _, item := range raw {
+		content, err := r.DecodeContent(item.Type, []byte(item.Data))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, content)
+	}
+	if r == DefaultContentRegistry {
+		return result, nil
+	}
+	return r.Resolve(result)
+}
+
+// Resolve 将内容中未知的类型按本注册表解码，包括合并转发中嵌套的消息
+func (r *ContentRegistry) Resolve(contents Contents) (Contents, error) {
+	result := make(Contents, 0, len(contents))
+	for _, content := range contents {
+		switch item := dereference(content).(type) {
+		case ContentUnknown:
+			resolved, err := r.DecodeContent(item.Type, []byte(item.Value))
+			if err != nil {
+				return nil, err
+			}
+			content = resolved
+		case ContentForward:
+			messages := make([]Message, len(item.Messages))
+			for i, message := range item.Messages {
+				resolved, err := r.Resolve(message.Content)
+				if err != nil {
+					return nil, err
+				}
+				message.Content = resolved
+				messages[i] = message
+			}
+			content = ContentForward{Messages: messages}
+		}
+		result = append(result, content)
+	}
+	return result, nil
+}
+
+// Encode 编码消息内容，ContentUnknown 按原样保留
+func (r *ContentRegistry) Encode(contents Contents) ([]byte, error) {
+	result := make(RAWContents, 0, len(contents))
+	for _, content := range contents {
+		key, ok := r.KeyOf(content)
+		if !ok {
+			if unknown, ok := dereference(content).(ContentUnknown); ok {
+				result = append(result, RawContent{
+					Type: unknown.Type,
+					Data: unknown.Value,
+				})
+				continue
+			}
+			return nil, fmt.Errorf("unknown type %T", content)
+		}
+		data, err := r.encodeContent(content)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, RawContent{
+			Type: key,
+			Data: string(data),
+		})
+	}
+	return json.Marshal(result)
+}
+
+// 编码单个内容，合并转发中嵌套的消息同样按本注册表编码
+func (r *ContentRegistry) encodeContent(content Content) ([]byte, error) {
+	forward, ok := dereference(content).(ContentForward)
+	if !ok || r == DefaultContentRegistry {
+		return json.Marshal(content)
+	}
+	lowered, err := r.lower(forward)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(lowered)
+}
+
+// 将合并转发中只在本注册表注册的类型转换为 ContentUnknown，使其可以按默认注册表编码
+//
+// 与 Resolve 相反，ContentUnknown 在解码时会被 Resolve 还原
+func (r *ContentRegistry) lower(forward ContentForward) (ContentForward, error) {
+	messages := make([]Message, len(forward.Messages))
+	for i, message := range forward.Messages {
+		contents := make(Contents, 0, len(message.Content))
+		for _, content := range message.Content {
+			if nested, ok := dereference(content).(ContentForward); ok {
+				lowered, err := r.lower(nested)
+				if err != nil {
+					return ContentForward{}, err
+				}
+				content = lowered
+			} else if _, ok := DefaultContentRegistry.KeyOf(content); !ok {
+				if key, ok := r.KeyOf(content); ok {
+					data, err := json.Marshal(content)
+					if err != nil {
+						return ContentForward{}, err
+					}
+					content = ContentUnknown{Type: key, Value: string(data)}
+				}
+			}
+			contents = append(contents, content)
+		}
+		message.Content = contents
+		messages[i] = message
+	}
+	return ContentForward{Messages: messages}, nil
 }
 
 type ContentUnknown struct {
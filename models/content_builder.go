@@ -0,0 +1,260 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ContentsBuilder 链式构建消息内容
+type ContentsBuilder struct {
+	contents Contents
+}
+
+func NewContentsBuilder() *ContentsBuilder {
+	return &ContentsBuilder{}
+}
+
+// Text 追加文本
+func (b *ContentsBuilder) Text(text string) *ContentsBuilder {
+	return b.Append(ContentText{Text: text})
+}
+
+// At 追加提及
+func (b *ContentsBuilder) At(uid string) *ContentsBuilder {
+	return b.Append(ContentAt{Uid: uid})
+}
+
+// Image 追加图片
+func (b *ContentsBuilder) Image(resource Resource) *ContentsBuilder {
+	return b.Append(ContentImage{Resource: resource})
+}
+
+// Reply 追加引用回复
+func (b *ContentsBuilder) Reply(messageID string) *ContentsBuilder {
+	return b.Append(ContentReply{MessageID: messageID})
+}
+
+// Face 追加表情
+func (b *ContentsBuilder) Face(id, name string) *ContentsBuilder {
+	return b.Append(ContentFace{ID: id, Name: name})
+}
+
+// File 追加文件
+func (b *ContentsBuilder) File(resource Resource, name string) *ContentsBuilder {
+	return b.Append(ContentFile{Resource: resource, Name: name})
+}
+
+// Audio 追加语音
+func (b *ContentsBuilder) Audio(resource Resource) *ContentsBuilder {
+	return b.Append(ContentAudio{Resource: resource})
+}
+
+// Video 追加视频
+func (b *ContentsBuilder) Video(resource Resource) *ContentsBuilder {
+	return b.Append(ContentVideo{Resource: resource})
+}
+
+// Location 追加位置
+func (b *ContentsBuilder) Location(latitude, longitude float64, name string) *ContentsBuilder {
+	return b.Append(ContentLocation{Latitude: latitude, Longitude: longitude, Name: name})
+}
+
+// Link 追加链接卡片
+func (b *ContentsBuilder) Link(url, title string) *ContentsBuilder {
+	return b.Append(ContentLinkCard{URL: url, Title: title})
+}
+
+// Forward 追加合并转发
+func (b *ContentsBuilder) Forward(messages ...Message) *ContentsBuilder {
+	return b.Append(ContentForward{Messages: messages})
+}
+
+// Append 追加任意内容
+func (b *ContentsBuilder) Append(contents ...Content) *ContentsBuilder {
+	b.contents = append(b.contents, contents...)
+	return b
+}
+
+// Build 返回构建的内容，构建器可以继续使用
+func (b *ContentsBuilder) Build() Contents {
+	return append(Contents(nil), b.contents...)
+}
+
+// 取出文本内容
+func contentText(content Content) (string, bool) {
+	switch text := content.(type) {
+	case ContentText:
+		return text.Text, true
+	case *ContentText:
+		return text.Text, true
+	}
+	return "", false
+}
+
+// Normalize 合并相邻文本并删除空文本
+func (contents Contents) Normalize() Contents {
+	result := make(Contents, 0, len(contents))
+	var builder strings.Builder
+	merging := false
+	flush := func() {
+		if merging && builder.Len() > 0 {
+			result = append(result, ContentText{Text: builder.String()})
+		}
+		builder.Reset()
+		merging = false
+	}
+	for _, content := range contents {
+		if text, ok := contentText(content); ok {
+			builder.WriteString(text)
+			merging = true
+			continue
+		}
+		flush()
+		result = append(result, content)
+	}
+	flush()
+	return result
+}
+
+// Split 按长度拆分内容，长度以 String() 的字符数计算
+//
+// 只有文本会被拆开，并优先在换行或空白处拆分，拆分处的空白保留在前一段末尾，
+// 下一段开头的空白会被去掉；其他内容保持完整，单个内容超过 maxLen 时独占一段
+func (contents Contents) Split(maxLen int) []Contents {
+	if maxLen <= 0 {
+		return []Contents{contents.Normalize()}
+	}
+	var result []Contents
+	var current Contents
+	size := 0
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, current.Normalize())
+		}
+		current = nil
+		size = 0
+	}
+	for _, content := range contents.Normalize() {
+		text, ok := contentText(content)
+		if !ok {
+			length := utf8.RuneCountInString(content.String())
+			if size > 0 && size+length > maxLen {
+				flush()
+			}
+			current = append(current, content)
+			size += length
+			continue
+		}
+		for text != "" {
+			if size >= maxLen {
+				// 拆分处的空白不放在下一段开头
+				flush()
+				text = strings.TrimLeftFunc(text, unicode.IsSpace)
+				continue
+			}
+			head, tail := splitText(text, maxLen-size, size == 0)
+			if head == "" {
+				flush()
+				text = strings.TrimLeftFunc(text, unicode.IsSpace)
+				continue
+			}
+			current = append(current, ContentText{Text: head})
+			size += utf8.RuneCountInString(head)
+			text = tail
+		}
+	}
+	flush()
+	return result
+}
+
+// 拆出不超过 room 个字符的前缀，force 为 false 时找不到合适的拆分点返回空前缀
+func splitText(text string, room int, force bool) (string, string) {
+	if utf8.RuneCountInString(text) <= room {
+		return text, ""
+	}
+	cut, count := 0, 0
+	for i := range text {
+		if count == room {
+			cut = i
+			break
+		}
+		count++
+	}
+	// 在窗口内最后一个空白之后拆分
+	if index := strings.LastIndexFunc(text[:cut], unicode.IsSpace); index >= 0 {
+		_, width := utf8.DecodeRuneInString(text[index:])
+		return text[:index+width], text[index+width:]
+	}
+	if !force {
+		return "", text
+	}
+	return text[:cut], text[cut:]
+}
+
+// Contains 判断文本内容中是否包含 substr，相邻文本视为连续
+func (contents Contents) Contains(substr string) bool {
+	for _, content := range contents.Normalize() {
+		if text, ok := contentText(content); ok && strings.Contains(text, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replace 替换文本内容中所有的 old，相邻文本视为连续，其他内容保持不变
+func (contents Contents) Replace(old, new string) Contents {
+	result := contents.Normalize()
+	for i, content := range result {
+		if text, ok := contentText(content); ok {
+			result[i] = ContentText{Text: strings.ReplaceAll(text, old, new)}
+		}
+	}
+	return result.Normalize()
+}
+
+// Mentions 返回提及的用户 ID，按首次出现的顺序去重
+func (contents Contents) Mentions() []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, content := range contents {
+		var uid string
+		switch at := content.(type) {
+		case ContentAt:
+			uid = at.Uid
+		case *ContentAt:
+			uid = at.Uid
+		default:
+			continue
+		}
+		if !seen[uid] {
+			seen[uid] = true
+			result = append(result, uid)
+		}
+	}
+	return result
+}
+
+// Equal 判断规范化后的内容是否相同
+func (contents Contents) Equal(other Contents) bool {
+	a, b := contents.Normalize(), other.Normalize()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(dereference(a[i]), dereference(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// 指针内容与值内容视为相同
+func dereference(content Content) any {
+	value := reflect.ValueOf(content)
+	if value.Kind() == reflect.Pointer && !value.IsNil() {
+		return value.Elem().Interface()
+	}
+	return content
+}
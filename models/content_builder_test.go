@@ -0,0 +1,122 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试链式构建
+func TestContentsBuilder(t *testing.T) {
+	resource := Resource{Scheme: "http", Body: "https://example.com/cat.png"}
+	builder := NewContentsBuilder().Text("hi ").At("u1").Image(resource)
+	assert.Equal(t, Contents{
+		ContentText{Text: "hi "},
+		ContentAt{Uid: "u1"},
+		ContentImage{Resource: resource},
+	}, builder.Build())
+
+	// Build 返回副本，继续构建不影响已返回的内容
+	first := builder.Build()
+	builder.Reply("m1").Face("14", "微笑")
+	assert.Len(t, first, 3)
+	assert.Len(t, builder.Build(), 5)
+}
+
+// 测试合并相邻文本与删除空文本
+func TestContentsNormalize(t *testing.T) {
+	contents := Contents{
+		ContentText{Text: ""},
+		ContentText{Text: "a"},
+		&ContentText{Text: "b"},
+		ContentAt{Uid: "u1"},
+		ContentText{Text: ""},
+		ContentAt{Uid: "u2"},
+		ContentText{Text: "c"},
+		ContentText{Text: "d"},
+	}
+	assert.Equal(t, Contents{
+		ContentText{Text: "ab"},
+		ContentAt{Uid: "u1"},
+		ContentAt{Uid: "u2"},
+		ContentText{Text: "cd"},
+	}, contents.Normalize())
+	assert.Empty(t, Contents{ContentText{}}.Normalize())
+}
+
+// 测试按长度拆分
+func TestContentsSplit(t *testing.T) {
+	contents := NewContentsBuilder().
+		Text("hello world ").
+		At("u1").
+		Text("abcdefghij").
+		Build()
+	// "@u1" 长度为 3，不会被拆开
+	assert.Equal(t, []Contents{
+		{ContentText{Text: "hello "}},
+		{ContentText{Text: "world "}, ContentAt{Uid: "u1"}},
+		{ContentText{Text: "abcdefghi"}},
+		{ContentText{Text: "j"}},
+	}, contents.Split(9))
+
+	// 超过长度的非文本内容独占一段
+	long := Contents{ContentText{Text: "ab"}, ContentLinkCard{URL: "https://example.com", Title: "x"}, ContentText{Text: "cd"}}
+	assert.Equal(t, []Contents{
+		{ContentText{Text: "ab"}},
+		{ContentLinkCard{URL: "https://example.com", Title: "x"}},
+		{ContentText{Text: "cd"}},
+	}, long.Split(5))
+
+	// 按字符而不是字节计算长度
+	assert.Equal(t, []Contents{
+		{ContentText{Text: "你好世"}},
+		{ContentText{Text: "界"}},
+	}, Contents{ContentText{Text: "你好世界"}}.Split(3))
+
+	// 拆分处的空白不会单独成段
+	assert.Equal(t, []Contents{
+		{ContentText{Text: "hello"}},
+		{ContentText{Text: "world"}},
+		{ContentText{Text: "foo"}},
+	}, Contents{ContentText{Text: "hello world foo"}}.Split(5))
+	assert.Equal(t, []Contents{
+		{ContentText{Text: "ab "}},
+		{ContentText{Text: "cd"}},
+	}, Contents{ContentText{Text: "ab    cd"}}.Split(3))
+}
+
+// 测试文本查找与替换
+func TestContentsReplace(t *testing.T) {
+	contents := Contents{
+		ContentText{Text: "foo b"},
+		ContentText{Text: "ar "},
+		ContentAt{Uid: "bar"},
+		ContentText{Text: " bar"},
+	}
+	assert.True(t, contents.Contains("bar"))
+	assert.False(t, contents.Contains("baz"))
+	assert.Equal(t, Contents{
+		ContentText{Text: "foo baz "},
+		ContentAt{Uid: "bar"},
+		ContentText{Text: " baz"},
+	}, contents.Replace("bar", "baz"))
+	// 替换为空后删除空文本
+	assert.Equal(t, Contents{ContentAt{Uid: "bar"}}, contents.Replace("foo bar ", "").Replace(" bar", ""))
+}
+
+// 测试提取提及与比较
+func TestContentsMentionsAndEqual(t *testing.T) {
+	contents := Contents{
+		ContentAt{Uid: "u1"},
+		ContentText{Text: "hi"},
+		&ContentAt{Uid: "u2"},
+		ContentAt{Uid: "u1"},
+	}
+	assert.Equal(t, []string{"u1", "u2"}, contents.Mentions())
+	assert.Nil(t, Contents{ContentText{Text: "x"}}.Mentions())
+
+	assert.True(t, Contents{ContentText{Text: "a"}, ContentText{Text: "b"}}.Equal(Contents{&ContentText{Text: "ab"}}))
+	assert.True(t, contents.Equal(Contents{ContentAt{Uid: "u1"}, ContentText{Text: "hi"}, ContentAt{Uid: "u2"}, ContentAt{Uid: "u1"}}))
+	assert.False(t, contents.Equal(Contents{ContentAt{Uid: "u1"}}))
+	assert.False(t, Contents{ContentText{Text: "a"}}.Equal(Contents{ContentText{Text: "b"}}))
+}
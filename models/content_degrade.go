@@ -0,0 +1,190 @@
+package models
+
+import (
+	"strconv"
+	"sync"
+)
+
+// ContentCapable 声明插件支持的内容类型，未实现该接口的插件视为支持所有类型
+//
+// 文本总是被支持，发送给插件的消息中不支持的内容会在投递前降级
+type ContentCapable interface {
+	// SupportedContents 返回支持的内容类型键
+	SupportedContents() []string
+}
+
+// Fallback 内容降级函数，返回替代的内容，替代内容仍不被支持时会继续降级
+type Fallback func(content Content) Contents
+
+// DegradeEntry 一次降级记录
+type DegradeEntry struct {
+	Index int      // 在原内容中的位置，合并转发中的内容为所在合并转发的位置
+	Path  []int    // 完整位置，合并转发中的内容依次为合并转发、消息与内容的位置，例如 [0 1 2]
+	From  string   // 原类型键
+	To    []string // 降级后的类型键，为空表示被删除
+}
+
+// DegradeReport 降级报告
+type DegradeReport struct {
+	Entries []DegradeEntry
+}
+
+// Empty 是否没有发生降级
+func (r DegradeReport) Empty() bool {
+	return len(r.Entries) == 0
+}
+
+// 降级的最大轮数，超过后直接转换为文本
+const maxDegradeDepth = 4
+
+// Degrader 按降级函数将不支持的内容转换为支持的内容
+//
+// 没有设置降级函数的类型转换为纯文本渲染结果
+type Degrader struct {
+	mu        sync.RWMutex
+	registry  *ContentRegistry
+	fallbacks map[string]Fallback
+}
+
+// NewDegrader 创建降级器，registry 用于识别内容类型，为 nil 时使用默认注册表
+func NewDegrader(registry *ContentRegistry) *Degrader {
+	if registry == nil {
+		registry = DefaultContentRegistry
+	}
+	return &Degrader{
+		registry:  registry,
+		fallbacks: make(map[string]Fallback),
+	}
+}
+
+// SetFallback 设置类型的降级函数，fallback 为 nil 时移除
+func (d *Degrader) SetFallback(key string, fallback Fallback) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if fallback == nil {
+		delete(d.fallbacks, key)
+		return
+	}
+	d.fallbacks[key] = fallback
+}
+
+// SupportedSet 返回判断类型键是否在 keys 中的函数
+func SupportedSet(keys ...string) func(key string) bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return func(key string) bool {
+		return set[key]
+	}
+}
+
+// Degrade 降级 supported 不支持的内容，合并转发中的消息同样会被降级
+//
+// 消息引用的消息（Message.Quote）只作为上下文，保持原样不降级
+func (d *Degrader) Degrade(contents Contents, supported func(key string) bool) (Contents, DegradeReport) {
+	var report DegradeReport
+	result := make(Contents, 0, len(contents))
+	for i, content := range contents {
+		key := d.keyOf(content)
+		if d.supports(key, supported) {
+			result = append(result, d.nested(content, i, supported, &report))
+			continue
+		}
+		degraded := d.degrade(content, key, supported, 0)
+		entry := DegradeEntry{Index: i, Path: []int{i}, From: key}
+		for _, item := range degraded {
+			entry.To = append(entry.To, d.keyOf(item))
+		}
+		report.Entries = append(report.Entries, entry)
+		result = append(result, degraded...)
+	}
+	return result, report
+}
+
+func (d *Degrader) keyOf(content Content) string {
+	if unknown, ok := dereference(content).(ContentUnknown); ok {
+		return unknown.Type
+	}
+	key, _ := d.registry.KeyOf(content)
+	return key
+}
+
+func (d *Degrader) supports(key string, supported func(key string) bool) bool {
+	return key == "text" || supported(key)
+}
+
+// 降级合并转发中嵌套的消息，index 为合并转发在原内容中的位置
+func (d *Degrader) nested(content Content, index int, supported func(key string) bool, report *DegradeReport) Content {
+	forward, ok := dereference(content).(ContentForward)
+	if !ok {
+		return content
+	}
+	messages := make([]Message, len(forward.Messages))
+	for i, message := range forward.Messages {
+		var nested DegradeReport
+		message.Content, nested = d.Degrade(message.Content, supported)
+		for _, entry := range nested.Entries {
+			entry.Index = index
+			entry.Path = append([]int{index, i}, entry.Path...)
+			report.Entries = append(report.Entries, entry)
+		}
+		messages[i] = message
+	}
+	return ContentForward{Messages: messages}
+}
+
+func (d *Degrader) degrade(content Content, key string, supported func(key string) bool, depth int) Contents {
+	d.mu.RLock()
+	fallback, ok := d.fallbacks[key]
+	d.mu.RUnlock()
+	if !ok || depth >= maxDegradeDepth {
+		if text := PlainRenderer.RenderContent(content); text != "" {
+			return Contents{ContentText{Text: text}}
+		}
+		return nil
+	}
+	var result Contents
+	for _, item := range fallback(dereference(content).(Content)) {
+		itemKey := d.keyOf(item)
+		if d.supports(itemKey, supported) {
+			result = append(result, item)
+			continue
+		}
+		result = append(result, d.degrade(item, itemKey, supported, depth+1)...)
+	}
+	return result
+}
+
+// DefaultDegrader 默认降级器：图片、文件、音视频降级为链接卡片，位置降级为带坐标的文本
+var DefaultDegrader = newDefaultDegrader()
+
+func newDefaultDegrader() *Degrader {
+	degrader := NewDegrader(nil)
+	degrader.SetFallback("image", func(content Content) Contents {
+		image := content.(ContentImage)
+		return Contents{ContentLinkCard{URL: image.Resource.Body, Title: orDefault(image.Summary, "image")}}
+	})
+	degrader.SetFallback("file", func(content Content) Contents {
+		file := content.(ContentFile)
+		return Contents{ContentLinkCard{URL: file.Resource.Body, Title: orDefault(file.Name, "file")}}
+	})
+	degrader.SetFallback("audio", func(content Content) Contents {
+		audio := content.(ContentAudio)
+		return Contents{ContentLinkCard{URL: audio.Resource.Body, Title: "audio"}}
+	})
+	degrader.SetFallback("video", func(content Content) Contents {
+		video := content.(ContentVideo)
+		return Contents{ContentLinkCard{URL: video.Resource.Body, Title: orDefault(video.Summary, "video")}}
+	})
+	degrader.SetFallback("location", func(content Content) Contents {
+		location := content.(ContentLocation)
+		coordinates := strconv.FormatFloat(location.Latitude, 'g', -1, 64) + "," +
+			strconv.FormatFloat(location.Longitude, 'g', -1, 64)
+		if location.Name == "" {
+			return Contents{ContentText{Text: coordinates}}
+		}
+		return Contents{ContentText{Text: location.Name + " (" + coordinates + ")"}}
+	})
+	return degrader
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试默认降级规则与降级报告
+func TestDegrade(t *testing.T) {
+	resource := Resource{Scheme: "https", Body: "https://example.com/cat.png"}
+	contents := Contents{
+		ContentText{Text: "look "},
+		ContentImage{Resource: resource, Summary: "cat"},
+		ContentLocation{Latitude: 1.5, Longitude: 2, Name: "home"},
+		ContentAt{Uid: "u1"},
+		ContentReply{MessageID: "m1"},
+		ContentUnknown{Type: "custom", Value: "{}"},
+	}
+
+	// 支持链接卡片时图片降级为链接卡片
+	degraded, report := DefaultDegrader.Degrade(contents, SupportedSet("link", "at"))
+	assert.Equal(t, Contents{
+		ContentText{Text: "look "},
+		ContentLinkCard{URL: "https://example.com/cat.png", Title: "cat"},
+		ContentText{Text: "home (1.5,2)"},
+		ContentAt{Uid: "u1"},
+		ContentText{Text: "unknown[type=custom]"},
+	}, degraded)
+	assert.Equal(t, []DegradeEntry{
+		{Index: 1, Path: []int{1}, From: "image", To: []string{"link"}},
+		{Index: 2, Path: []int{2}, From: "location", To: []string{"text"}},
+		{Index: 4, Path: []int{4}, From: "reply"},
+		{Index: 5, Path: []int{5}, From: "custom", To: []string{"text"}},
+	}, report.Entries)
+
+	// 只支持文本时链接卡片继续降级为文本
+	degraded, report = DefaultDegrader.Degrade(Contents{&ContentImage{Resource: resource}}, SupportedSet())
+	assert.Equal(t, Contents{ContentText{Text: "image (https://example.com/cat.png)"}}, degraded)
+	assert.Equal(t, []DegradeEntry{{Index: 0, Path: []int{0}, From: "image", To: []string{"text"}}}, report.Entries)
+
+	// 全部支持时不降级
+	degraded, report = DefaultDegrader.Degrade(contents[:2], SupportedSet("image"))
+	assert.True(t, report.Empty())
+	assert.Equal(t, contents[:2], degraded)
+}
+
+// 测试自定义降级函数与合并转发中的降级
+func TestDegradeCustom(t *testing.T) {
+	degrader := NewDegrader(nil)
+	degrader.SetFallback("face", func(content Content) Contents {
+		return Contents{ContentImage{Resource: *content.(ContentFace).Resource}}
+	})
+	degrader.SetFallback("image", func(content Content) Contents {
+		return Contents{ContentText{Text: "<img>"}}
+	})
+	sticker := Resource{Scheme: "https", Body: "https://example.com/s.png"}
+	contents := Contents{ContentForward{Messages: []Message{{ID: "m1", Content: Contents{ContentFace{ID: "1", Resource: &sticker}}}}}}
+
+	degraded, report := degrader.Degrade(contents, SupportedSet("forward", "image"))
+	assert.Equal(t, Contents{ContentForward{Messages: []Message{{ID: "m1", Content: Contents{ContentImage{Resource: sticker}}}}}}, degraded)
+	assert.Equal(t, []DegradeEntry{{Index: 0, Path: []int{0, 0, 0}, From: "face", To: []string{"image"}}}, report.Entries)
+	// 原内容没有被修改
+	assert.Equal(t, ContentFace{ID: "1", Resource: &sticker}, contents[0].(ContentForward).Messages[0].Content[0])
+
+	// 多轮降级
+	degraded, _ = degrader.Degrade(contents[0].(ContentForward).Messages[0].Content, SupportedSet())
+	assert.Equal(t, Contents{ContentText{Text: "<img>"}}, degraded)
+}
+
+// 测试合并转发中降级记录的位置
+func TestDegradeNestedPath(t *testing.T) {
+	image := ContentImage{Resource: Resource{Scheme: "https", Body: "https://example.com/a.png"}}
+	quote := &Message{ID: "q1", Content: Contents{image}}
+	contents := Contents{
+		image,
+		ContentForward{Messages: []Message{
+			{ID: "m1", Content: Contents{ContentText{Text: "a"}}},
+			{ID: "m2", Content: Contents{ContentText{Text: "b"}, image}, Quote: quote},
+			{ID: "m3", Content: Contents{ContentForward{Messages: []Message{{ID: "m4", Content: Contents{image}}}}}},
+		}},
+	}
+
+	degraded, report := DefaultDegrader.Degrade(contents, SupportedSet("forward", "link"))
+	assert.Equal(t, []DegradeEntry{
+		{Index: 0, Path: []int{0}, From: "image", To: []string{"link"}},
+		{Index: 1, Path: []int{1, 1, 1}, From: "image", To: []string{"link"}},
+		{Index: 1, Path: []int{1, 2, 0, 0, 0}, From: "image", To: []string{"link"}},
+	}, report.Entries)
+	// 引用的消息保持原样
+	assert.Same(t, quote, degraded[1].(ContentForward).Messages[1].Quote)
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type qqFace struct {
+	ID   int  `json:"id"`
+	Big  bool `json:"big"`
+	Name string
+}
+
+func (c qqFace) String() string {
+	return fmt.Sprintf("qq.face[%d]", c.ID)
+}
+
+// 测试独立注册表的注册与命名空间
+func TestContentRegistry(t *testing.T) {
+	registry := DefaultContentRegistry.Clone()
+	assert.NoError(t, registry.Register("qq.face", reflect.TypeOf((*qqFace)(nil))))
+	// 副本的注册不影响默认注册表
+	_, ok := DefaultContentRegistry.Lookup("qq.face")
+	assert.False(t, ok)
+	typeOf, ok := registry.Lookup("qq.face")
+	assert.True(t, ok)
+	assert.Equal(t, reflect.TypeOf(qqFace{}), typeOf)
+	key, ok := registry.KeyOf(&qqFace{})
+	assert.True(t, ok)
+	assert.Equal(t, "qq.face", key)
+	assert.Contains(t, registry.Keys(), "qq.face")
+	assert.Contains(t, registry.Keys(), "text")
+
+	assert.EqualError(t, registry.Register("qq.face", reflect.TypeOf(ContentText{})), "duplicate key qq.face")
+	assert.Error(t, registry.Register("qq.face2", reflect.TypeOf(qqFace{})))
+	for _, key := range []string{"", "QQ.face", "qq..face", ".face", "qq face"} {
+		assert.Error(t, registry.Register(key, reflect.TypeOf(ContentUnknown{})), key)
+	}
+	assert.Error(t, registry.Register("number", reflect.TypeOf(0)))
+}
+
+// 测试使用指定注册表编解码
+func TestContentRegistryDecode(t *testing.T) {
+	registry := NewContentRegistry()
+	assert.NoError(t, registry.Register("text", reflect.TypeOf(ContentText{})))
+	assert.NoError(t, registry.Register("qq.face", reflect.TypeOf(qqFace{})))
+
+	contents := Contents{ContentText{Text: "hi"}, qqFace{ID: 14, Big: true}}
+	data, err := registry.Encode(contents)
+	assert.NoError(t, err)
+	decoded, err := registry.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, contents, decoded)
+
+	// 默认注册表不认识 qq.face，保留为 ContentUnknown 并可以原样编码
+	var fallback Contents
+	assert.NoError(t, json.Unmarshal(data, &fallback))
+	assert.Equal(t, ContentUnknown{Type: "qq.face", Value: `{"id":14,"big":true,"Name":""}`}, fallback[1])
+	encoded, err := json.Marshal(&fallback)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(data), string(encoded))
+
+	// 没有注册的类型无法编码
+	_, err = registry.Encode(Contents{ContentAt{Uid: "u1"}})
+	assert.EqualError(t, err, "unknown type models.ContentAt")
+
+	// 合并转发中嵌套的未知类型同样被解析
+	var nested Contents
+	assert.NoError(t, json.Unmarshal([]byte(`[{"type":"forward","data":"{\"messages\":[{\"id\":\"m1\",\"content\":`+
+		`[{\"type\":\"qq.face\",\"data\":\"{\\\"id\\\":1}\"}]}]}"}]`), &nested))
+	resolved, err := registry.Resolve(nested)
+	assert.NoError(t, err)
+	assert.Equal(t, Contents{qqFace{ID: 1}}, resolved[0].(ContentForward).Messages[0].Content)
+}
+
+// 测试并发注册与查询
+func TestContentRegistryConcurrent(t *testing.T) {
+	registry := NewContentRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = registry.Register("text", reflect.TypeOf(ContentText{}))
+			_, _ = registry.Lookup("text")
+			_, _ = registry.Decode([]byte(`[{"type":"text","data":"{\"text\":\"x\"}"}]`))
+			_, _ = registry.ParseMarkup(`[text{"text":"x"}]`)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"text"}, registry.Keys())
+}
+
+// 测试默认注册表保持重复注册时 panic 的行为
+func TestRegisterContentPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterContent("text", reflect.TypeOf(ContentText{}))
+	})
+}
+
+// 测试合并转发中嵌套的自定义类型按注册表编码
+func TestContentRegistryEncodeForward(t *testing.T) {
+	registry := DefaultContentRegistry.Clone()
+	assert.NoError(t, registry.Register("qq.face", reflect.TypeOf(qqFace{})))
+	contents := Contents{ContentForward{Messages: []Message{
+		{ID: "m1", Content: Contents{qqFace{ID: 1}, ContentForward{Messages: []Message{
+			{ID: "m2", Content: Contents{ContentText{Text: "hi"}, qqFace{ID: 2}}},
+		}}}},
+	}}}
+	data, err := registry.Encode(contents)
+	assert.NoError(t, err)
+	decoded, err := registry.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, contents, decoded)
+
+	// 默认注册表中保留为 ContentUnknown
+	var fallback Contents
+	assert.NoError(t, json.Unmarshal(data, &fallback))
+	assert.Equal(t, ContentUnknown{Type: "qq.face", Value: `{"id":1,"big":false,"Name":""}`},
+		fallback[0].(ContentForward).Messages[0].Content[0])
+}
+
+// 测试标记语法使用注册表中的类型与简写形式
+func TestContentRegistryMarkup(t *testing.T) {
+	registry := DefaultContentRegistry.Clone()
+	assert.NoError(t, registry.Register("qq.face", reflect.TypeOf(qqFace{})))
+	assert.NoError(t, registry.RegisterMarkup("qq.face", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			id, err := strconv.Atoi(value)
+			return qqFace{ID: id}, err
+		},
+		Format: func(content Content) (string, bool) {
+			face := content.(qqFace)
+			return strconv.Itoa(face.ID), !face.Big && face.Name == ""
+		},
+	}))
+	assert.EqualError(t, registry.RegisterMarkup("qq.face", MarkupShorthand{
+		Parse:  func(string) (Content, error) { return nil, nil },
+		Format: func(Content) (string, bool) { return "", false },
+	}), "duplicate markup qq.face")
+	assert.Error(t, registry.RegisterMarkup("qq.empty", MarkupShorthand{}))
+
+	contents, err := registry.ParseMarkup(`hi [qq.face:14][qq.face{"id":1,"big":true}][at:u1]`)
+	assert.NoError(t, err)
+	assert.Equal(t, Contents{ContentText{Text: "hi "}, qqFace{ID: 14}, qqFace{ID: 1, Big: true}, ContentAt{Uid: "u1"}}, contents)
+	text, err := registry.FormatMarkup(contents)
+	assert.NoError(t, err)
+	assert.Equal(t, `hi [qq.face:14][qq.face{"id":1,"big":true,"Name":""}][at:u1]`, text)
+
+	// 默认注册表不受影响
+	_, err = ParseMarkup("[qq.face:14]")
+	assert.Error(t, err)
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试所有内容类型的 JSON 往返
+func TestContentsRoundTrip(t *testing.T) {
+	resource := Resource{PluginID: 1, Scheme: "http", Body: "https://example.com/blob"}
+	contents := Contents{
+		ContentText{Text: "hello "},
+		ContentAt{Uid: "u1", User: &User{Id: "u1", Name: "Alice"}},
+		ContentImage{Resource: resource, Summary: "cat"},
+		ContentReply{MessageID: "m1", Summary: "earlier"},
+		ContentFace{ID: "14", Name: "微笑"},
+		ContentFace{ID: "sticker-1", Resource: &resource},
+		ContentFile{Resource: resource, Name: "report.pdf", Size: 1024},
+		ContentAudio{Resource: resource, Duration: 3 * time.Second},
+		ContentVideo{Resource: resource, Cover: &resource, Duration: time.Minute, Summary: "clip"},
+		ContentLocation{Latitude: 31.2304, Longitude: 121.4737, Name: "上海", Address: "人民广场"},
+		ContentLinkCard{URL: "https://example.com", Title: "Example", Description: "desc", Image: &resource},
+		ContentUnknown{Type: "custom", Value: `{"x":1}`},
+	}
+	data, err := json.Marshal(&contents)
+	assert.NoError(t, err)
+
+	var decoded Contents
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, contents, decoded)
+
+	var raw RAWContents
+	assert.NoError(t, json.Unmarshal(data, &raw))
+	var types []string
+	for _, item := range raw {
+		types = append(types, item.Type)
+	}
+	assert.Equal(t, []string{
+		"text", "at", "image", "reply", "face", "face", "file",
+		"audio", "video", "location", "link", "custom",
+	}, types)
+}
+
+// 测试不支持富内容时的纯文本表示
+func TestContentsString(t *testing.T) {
+	contents := Contents{
+		ContentReply{MessageID: "m1"},
+		ContentText{Text: "see "},
+		ContentFace{ID: "14", Name: "微笑"},
+		ContentFace{ID: "15"},
+		ContentFile{Name: "a.txt"},
+		ContentAudio{Duration: 2 * time.Second},
+		ContentVideo{Summary: "clip"},
+		ContentLocation{Name: "home", Latitude: 1.5, Longitude: -2},
+		ContentLinkCard{URL: "https://example.com", Title: "Example"},
+	}
+	assert.Equal(t, "reply[id=m1]see [微笑]face[id=15]file[name=a.txt,blob]audio[duration=2s,blob]"+
+		"video[summary=clip,blob]location[name=home,lat=1.5,lng=-2]link[title=Example,url=https://example.com]",
+		contents.String())
+}
+
+// 测试合并转发消息的嵌套往返
+func TestContentForwardRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	inner := ContentForward{Messages: []Message{{
+		ID:      "m1",
+		Owner:   &GuildMember{User: &User{Id: "u1", Name: "Alice"}},
+		Content: Contents{ContentText{Text: "inner"}},
+		Created: created,
+	}}}
+	outer := ContentForward{Messages: []Message{
+		{
+			ID:      "m2",
+			Owner:   &GuildMember{User: &User{Id: "u2", Name: "Bob"}, GuildName: "bob"},
+			Guild:   &Guild{Id: "g1", Name: "group"},
+			Content: Contents{ContentText{Text: "look"}, ContentImage{Summary: "cat"}},
+			Created: created,
+		},
+		{
+			ID:      "m3",
+			Content: Contents{inner},
+			Created: created.Add(time.Minute),
+		},
+	}}
+	assert.Equal(t, 2, outer.Depth())
+
+	contents := Contents{outer}
+	data, err := json.Marshal(&contents)
+	assert.NoError(t, err)
+	var decoded Contents
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, contents, decoded)
+	assert.Equal(t, "forward[messages=2]", decoded.String())
+}
+
+// 测试合并转发消息的嵌套层数限制
+func TestContentForwardDepth(t *testing.T) {
+	forward := ContentForward{Messages: []Message{{ID: "leaf"}}}
+	for i := 1; i < MaxForwardDepth; i++ {
+		forward = ContentForward{Messages: []Message{{ID: "node", Content: Contents{forward}}}}
+	}
+	assert.Equal(t, MaxForwardDepth, forward.Depth())
+	data, err := json.Marshal(&Contents{forward})
+	assert.NoError(t, err)
+
+	// 超过层数限制时编码与解码都失败
+	tooDeep := ContentForward{Messages: []Message{{ID: "node", Content: Contents{forward}}}}
+	_, err = json.Marshal(&Contents{tooDeep})
+	assert.Error(t, err)
+
+	raw, err := json.Marshal(RAWContents{{Type: "forward", Data: `{"messages":[{"id":"node","content":` + string(data) + `}]}`}})
+	assert.NoError(t, err)
+	var decoded Contents
+	assert.ErrorContains(t, json.Unmarshal(raw, &decoded), "forward depth 9 exceeds 8")
+
+	// 远超层数限制的输入在到达限制时即停止解码，不会解析到最内层的无效数据
+	nested := `{"id":"leaf","created":"invalid"}`
+	for range 16 {
+		data, err := json.Marshal(RAWContents{{Type: "forward", Data: `{"messages":[` + nested + `]}`}})
+		assert.NoError(t, err)
+		nested = `{"id":"node","content":` + string(data) + `}`
+	}
+	raw, err = json.Marshal(RAWContents{{Type: "forward", Data: `{"messages":[` + nested + `]}`}})
+	assert.NoError(t, err)
+	assert.EqualError(t, json.Unmarshal(raw, &decoded), "forward depth 9 exceeds 8")
+	quotedRaw := `{"messages":[{"id":"q","quote":{"id":"node","content":` + string(data) + `}}]}`
+	var quotedForward ContentForward
+	assert.ErrorContains(t, json.Unmarshal([]byte(quotedRaw), &quotedForward), "forward depth 9 exceeds 8")
+
+	// 引用中的合并转发同样计入层数
+	quoted := ContentForward{Messages: []Message{{ID: "q", Quote: &Message{Content: Contents{forward}}}}}
+	assert.Equal(t, MaxForwardDepth+1, quoted.Depth())
+}
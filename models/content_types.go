@@ -1,8 +1,10 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 type ContentText struct {
@@ -31,8 +33,220 @@ func (c ContentImage) String() string {
 	return fmt.Sprintf("image[summary=%s,blob]", c.Summary)
 }
 
+// ContentReply 引用回复
+type ContentReply struct {
+	MessageID string `json:"id"`
+	Summary   string `json:"summary,omitempty"` // 被引用消息的摘要
+}
+
+func (c ContentReply) String() string {
+	return fmt.Sprintf("reply[id=%s]", c.MessageID)
+}
+
+// ContentFace 平台表情或贴纸
+type ContentFace struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Resource *Resource `json:"data,omitempty"` // 贴纸等带图片的表情
+}
+
+func (c ContentFace) String() string {
+	if c.Name != "" {
+		return fmt.Sprintf("[%s]", c.Name)
+	}
+	return fmt.Sprintf("face[id=%s]", c.ID)
+}
+
+// ContentFile 文件
+type ContentFile struct {
+	Resource Resource `json:"data"`
+	Name     string   `json:"name"`
+	Size     int64    `json:"size,omitempty"`
+}
+
+func (c ContentFile) String() string {
+	return fmt.Sprintf("file[name=%s,blob]", c.Name)
+}
+
+// ContentAudio 语音或音频
+type ContentAudio struct {
+	Resource Resource      `json:"data"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+func (c ContentAudio) String() string {
+	return fmt.Sprintf("audio[duration=%s,blob]", c.Duration)
+}
+
+// ContentVideo 视频
+type ContentVideo struct {
+	Resource Resource      `json:"data"`
+	Cover    *Resource     `json:"cover,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Summary  string        `json:"summary,omitempty"`
+}
+
+func (c ContentVideo) String() string {
+	return fmt.Sprintf("video[summary=%s,blob]", c.Summary)
+}
+
+// ContentLocation 位置
+type ContentLocation struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+func (c ContentLocation) String() string {
+	return fmt.Sprintf("location[name=%s,lat=%g,lng=%g]", c.Name, c.Latitude, c.Longitude)
+}
+
+// ContentLinkCard 链接卡片
+type ContentLinkCard struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Image       *Resource `json:"image,omitempty"`
+}
+
+func (c ContentLinkCard) String() string {
+	return fmt.Sprintf("link[title=%s,url=%s]", c.Title, c.URL)
+}
+
+// MaxForwardDepth 合并转发消息的最大嵌套层数
+const MaxForwardDepth = 8
+
+// ContentForward 合并转发消息，保留原始消息的作者与时间
+type ContentForward struct {
+	Messages []Message `json:"messages"`
+}
+
+func (c ContentForward) String() string {
+	return fmt.Sprintf("forward[messages=%d]", len(c.Messages))
+}
+
+// Depth 返回嵌套层数，不包含其他合并转发时为 1
+func (c ContentForward) Depth() int {
+	depth := 0
+	for i := range c.Messages {
+		depth = max(depth, forwardDepth(&c.Messages[i]))
+	}
+	return depth + 1
+}
+
+// 消息内容及其引用中合并转发的最大层数
+func forwardDepth(message *Message) int {
+	depth := 0
+	for message != nil {
+		for _, content := range message.Content {
+			switch forward := content.(type) {
+			case ContentForward:
+				depth = max(depth, forward.Depth())
+			case *ContentForward:
+				depth = max(depth, forward.Depth())
+			}
+		}
+		message = message.Quote
+	}
+	return depth
+}
+
+type jsonContentForward ContentForward
+
+func (c ContentForward) MarshalJSON() ([]byte, error) {
+	if depth := c.Depth(); depth > MaxForwardDepth {
+		return nil, fmt.Errorf("forward depth %d exceeds %d", depth, MaxForwardDepth)
+	}
+	return json.Marshal(jsonContentForward(c))
+}
+
+func (c *ContentForward) UnmarshalJSON(data []byte) error {
+	forward, err := decodeForward(data, 1)
+	if err != nil {
+		return err
+	}
+	*c = forward
+	return nil
+}
+
+type jsonMessage Message
+
+// 内容延后解码的消息，用于在解码嵌套的合并转发前检查层数
+type jsonForwardMessage struct {
+	jsonMessage
+	Content json.RawMessage     `json:"content"`
+	Quote   *jsonForwardMessage `json:"quote,omitempty"`
+}
+
+// 解码第 depth 层合并转发，超过层数限制时在解码内层消息前返回错误
+func decodeForward(data []byte, depth int) (ContentForward, error) {
+	if depth > MaxForwardDepth {
+		return ContentForward{}, fmt.Errorf("forward depth %d exceeds %d", depth, MaxForwardDepth)
+	}
+	var forward struct {
+		Messages []jsonForwardMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &forward); err != nil {
+		return ContentForward{}, err
+	}
+	var result ContentForward
+	if forward.Messages != nil {
+		result.Messages = make([]Message, 0, len(forward.Messages))
+	}
+	for i := range forward.Messages {
+		message, err := decodeForwardMessage(&forward.Messages[i], depth)
+		if err != nil {
+			return ContentForward{}, err
+		}
+		result.Messages = append(result.Messages, *message)
+	}
+	return result, nil
+}
+
+// 解码合并转发中的消息，消息及其引用中的合并转发位于第 depth+1 层
+func decodeForwardMessage(raw *jsonForwardMessage, depth int) (*Message, error) {
+	message := Message(raw.jsonMessage)
+	if len(raw.Content) > 0 {
+		var items RAWContents
+		if err := json.Unmarshal(raw.Content, &items); err != nil {
+			return nil, err
+		}
+		message.Content = make(Contents, 0, len(items))
+		for _, item := range items {
+			var content Content
+			var err error
+			if typeOf, ok := DefaultContentRegistry.Lookup(item.Type); ok && typeOf == reflect.TypeFor[ContentForward]() {
+				content, err = decodeForward([]byte(item.Data), depth+1)
+			} else {
+				content, err = DefaultContentRegistry.DecodeContent(item.Type, []byte(item.Data))
+			}
+			if err != nil {
+				return nil, err
+			}
+			message.Content = append(message.Content, content)
+		}
+	}
+	if raw.Quote != nil {
+		quote, err := decodeForwardMessage(raw.Quote, depth)
+		if err != nil {
+			return nil, err
+		}
+		message.Quote = quote
+	}
+	return &message, nil
+}
+
 func init() {
 	RegisterContent("text", reflect.TypeOf((*ContentText)(nil)))
 	RegisterContent("at", reflect.TypeOf((*ContentAt)(nil)))
 	RegisterContent("image", reflect.TypeOf((*ContentImage)(nil)))
+	RegisterContent("reply", reflect.TypeOf((*ContentReply)(nil)))
+	RegisterContent("face", reflect.TypeOf((*ContentFace)(nil)))
+	RegisterContent("file", reflect.TypeOf((*ContentFile)(nil)))
+	RegisterContent("audio", reflect.TypeOf((*ContentAudio)(nil)))
+	RegisterContent("video", reflect.TypeOf((*ContentVideo)(nil)))
+	RegisterContent("location", reflect.TypeOf((*ContentLocation)(nil)))
+	RegisterContent("link", reflect.TypeOf((*ContentLinkCard)(nil)))
+	RegisterContent("forward", reflect.TypeOf((*ContentForward)(nil)))
 }
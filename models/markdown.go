@@ -0,0 +1,178 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseMarkdown 将 Markdown 解析为消息内容
+//
+// 图片解析为 ContentImage，链接与自动链接解析为 ContentLinkCard，
+// 反斜杠转义与行内代码中的文本按原样保留；成对且位于词边界的 **、~~ 与 __
+// 强调标记被移除，__ 只在包含空白时视为强调，避免误删 __init__ 等标识符；
+// 其余语法作为普通文本
+func ParseMarkdown(src string) Contents {
+	result := make(Contents, 0)
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			result = append(result, ContentText{Text: text.String()})
+			text.Reset()
+		}
+	}
+	closers := make(map[int]bool) // 已匹配的强调结束标记位置
+	for pos := 0; pos < len(src); {
+		rest := src[pos:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && isMarkdownPunct(rest[1]):
+			text.WriteByte(rest[1])
+			pos += 2
+			continue
+		case closers[pos]:
+			pos += 2
+			continue
+		case strings.HasPrefix(rest, "**"), strings.HasPrefix(rest, "__"), strings.HasPrefix(rest, "~~"):
+			if end, ok := matchMarkdownEmphasis(src, pos); ok {
+				closers[end] = true
+				pos += 2
+				continue
+			}
+		case rest[0] == '`':
+			if code, size, ok := parseMarkdownCode(rest); ok {
+				text.WriteString(code)
+				pos += size
+				continue
+			}
+		case strings.HasPrefix(rest, "!["):
+			if label, url, size, ok := parseMarkdownLink(rest[1:]); ok {
+				flush()
+				resource, err := parseMarkupResource(url)
+				if err != nil {
+					resource = Resource{Body: url}
+				}
+				result = append(result, ContentImage{Resource: resource, Summary: label})
+				pos += size + 1
+				continue
+			}
+		case rest[0] == '[':
+			if label, url, size, ok := parseMarkdownLink(rest); ok {
+				flush()
+				// 标题与地址相同的链接视为无标题链接
+				if label == url {
+					label = ""
+				}
+				result = append(result, ContentLinkCard{URL: url, Title: label})
+				pos += size
+				continue
+			}
+		case rest[0] == '<':
+			if end := strings.IndexByte(rest, '>'); end > 0 {
+				url := rest[1:end]
+				if strings.Contains(url, ":") && !strings.ContainsAny(url, " \t\n<") {
+					flush()
+					result = append(result, ContentLinkCard{URL: url})
+					pos += end + 1
+					continue
+				}
+			}
+		}
+		text.WriteByte(rest[0])
+		pos++
+	}
+	flush()
+	return result
+}
+
+// 查找 pos 处强调开始标记对应的结束标记，开始标记需位于词首，结束标记需位于词尾，
+// 跳过转义、行内代码与链接
+func matchMarkdownEmphasis(src string, pos int) (int, bool) {
+	marker := src[pos : pos+2]
+	if before, _ := utf8.DecodeLastRuneInString(src[:pos]); pos > 0 && isWordRune(before) {
+		return 0, false
+	}
+	if after, _ := utf8.DecodeRuneInString(src[pos+2:]); pos+2 >= len(src) || unicode.IsSpace(after) {
+		return 0, false
+	}
+	for i := pos + 2; i < len(src); i++ {
+		switch {
+		case src[i] == '\\' && i+1 < len(src) && isMarkdownPunct(src[i+1]):
+			i++
+		case src[i] == '`':
+			if _, size, ok := parseMarkdownCode(src[i:]); ok {
+				i += size - 1
+			}
+		case src[i] == '[':
+			// 链接标题中的标记属于标题文本
+			if _, _, size, ok := parseMarkdownLink(src[i:]); ok {
+				i += size - 1
+			}
+		case strings.HasPrefix(src[i:], marker) && i > pos+2:
+			before, _ := utf8.DecodeLastRuneInString(src[:i])
+			after, _ := utf8.DecodeRuneInString(src[i+2:])
+			if unicode.IsSpace(before) || i+2 < len(src) && isWordRune(after) {
+				continue
+			}
+			if marker == "__" && !strings.ContainsFunc(src[pos+2:i], unicode.IsSpace) {
+				return 0, false
+			}
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func isMarkdownPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// 解析行内代码，返回代码文本与占用的长度
+func parseMarkdownCode(src string) (string, int, bool) {
+	fence := len(src) - len(strings.TrimLeft(src, "`"))
+	end := strings.Index(src[fence:], src[:fence])
+	if end < 0 {
+		return "", 0, false
+	}
+	return src[fence : fence+end], fence*2 + end, true
+}
+
+// 解析 [标题](地址)，返回去除转义后的标题、地址与占用的长度
+func parseMarkdownLink(src string) (string, string, int, bool) {
+	label, pos, ok := scanMarkdownUntil(src, 1, ']')
+	if !ok || pos >= len(src) || src[pos] != '(' {
+		return "", "", 0, false
+	}
+	url, end, ok := scanMarkdownUntil(src, pos+1, ')')
+	if !ok {
+		return "", "", 0, false
+	}
+	url = strings.TrimSpace(url)
+	if len(url) >= 2 && url[0] == '<' && url[len(url)-1] == '>' {
+		url = url[1 : len(url)-1]
+	}
+	return label, url, end, true
+}
+
+// 从 start 开始读取到未转义的 stop，返回去除转义后的文本与 stop 之后的位置
+func scanMarkdownUntil(src string, start int, stop byte) (string, int, bool) {
+	var builder strings.Builder
+	for pos := start; pos < len(src); pos++ {
+		switch {
+		case src[pos] == '\\' && pos+1 < len(src) && isMarkdownPunct(src[pos+1]):
+			pos++
+			builder.WriteByte(src[pos])
+		case src[pos] == stop:
+			return builder.String(), pos + 1, true
+		case src[pos] == '\n' && pos+1 < len(src) && src[pos+1] == '\n':
+			// 链接不能跨段落
+			return "", 0, false
+		default:
+			builder.WriteByte(src[pos])
+		}
+	}
+	return "", 0, false
+}
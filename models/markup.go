@@ -0,0 +1,374 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 消息标记语法
+//
+//	Hello [at:123] see [image:file:///tmp/x.png]
+//
+// 文本中的 [、] 与 \ 需要用 \ 转义。标签有两种形式：
+//
+//	[类型:值]      简写形式，仅支持注册了简写的类型，值中的 ] 与 \ 需要转义
+//	[类型{JSON}]   通用形式，支持注册表中的所有类型，未注册的类型解析为 ContentUnknown
+//
+// 类型与简写形式保存在 ContentRegistry 中，包级函数使用默认注册表
+
+// MarkupShorthand 内容类型的简写形式
+type MarkupShorthand struct {
+	// Parse 将简写值解析为内容
+	Parse func(value string) (Content, error)
+	// Format 将内容格式化为简写值，无法无损表示时返回 false
+	Format func(content Content) (string, bool)
+}
+
+// RegisterMarkup 在默认注册表中注册内容类型的简写形式，注册失败时 panic
+func RegisterMarkup(key string, shorthand MarkupShorthand) {
+	if err := DefaultContentRegistry.RegisterMarkup(key, shorthand); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterMarkup 注册内容类型的简写形式
+func (r *ContentRegistry) RegisterMarkup(key string, shorthand MarkupShorthand) error {
+	if !validContentKey(key) {
+		return fmt.Errorf("invalid content key %q", key)
+	}
+	if shorthand.Parse == nil || shorthand.Format == nil {
+		return fmt.Errorf("markup %s must have Parse and Format", key)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.markups[key]; ok {
+		return fmt.Errorf("duplicate markup %s", key)
+	}
+	r.markups[key] = shorthand
+	return nil
+}
+
+// 返回类型键的简写形式
+func (r *ContentRegistry) markup(key string) (MarkupShorthand, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	shorthand, ok := r.markups[key]
+	return shorthand, ok
+}
+
+// MarkupError 标记解析错误
+type MarkupError struct {
+	Offset int // 字节偏移
+	Line   int // 行号，从 1 开始
+	Column int // 列号，按字符计算，从 1 开始
+	Msg    string
+}
+
+func (e *MarkupError) Error() string {
+	return fmt.Sprintf("markup: line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func markupError(src string, offset int, format string, args ...any) error {
+	prefix := src[:offset]
+	line := strings.Count(prefix, "\n") + 1
+	column := utf8.RuneCountInString(prefix[strings.LastIndexByte(prefix, '\n')+1:]) + 1
+	return &MarkupError{Offset: offset, Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// ParseMarkup 使用默认注册表将标记文本解析为消息内容
+func ParseMarkup(src string) (Contents, error) {
+	return DefaultContentRegistry.ParseMarkup(src)
+}
+
+// ParseMarkup 将标记文本解析为消息内容，类型与简写形式按本注册表查找
+func (r *ContentRegistry) ParseMarkup(src string) (Contents, error) {
+	result := make(Contents, 0)
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			result = append(result, ContentText{Text: text.String()})
+			text.Reset()
+		}
+	}
+	for pos := 0; pos < len(src); {
+		switch src[pos] {
+		case '\\':
+			if pos+1 >= len(src) || !isMarkupEscape(src[pos+1]) {
+				return nil, markupError(src, pos, "invalid escape")
+			}
+			text.WriteByte(src[pos+1])
+			pos += 2
+		case ']':
+			return nil, markupError(src, pos, "unexpected ]")
+		case '[':
+			content, next, err := r.parseMarkupTag(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			result = append(result, content)
+			pos = next
+		default:
+			text.WriteByte(src[pos])
+			pos++
+		}
+	}
+	flush()
+	if r == DefaultContentRegistry {
+		return result, nil
+	}
+	// 通用形式的合并转发中嵌套的未知类型按本注册表解析
+	return r.Resolve(result)
+}
+
+func isMarkupEscape(c byte) bool {
+	return c == '[' || c == ']' || c == '\\'
+}
+
+func isMarkupKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.'
+}
+
+// 解析 start 处的标签，返回内容与标签之后的位置
+func (r *ContentRegistry) parseMarkupTag(src string, start int) (Content, int, error) {
+	pos := start + 1
+	for pos < len(src) && isMarkupKey(src[pos]) {
+		pos++
+	}
+	key := src[start+1 : pos]
+	if key == "" {
+		return nil, 0, markupError(src, start+1, "missing content type")
+	}
+	if pos >= len(src) {
+		return nil, 0, markupError(src, start, "unterminated tag")
+	}
+	switch src[pos] {
+	case ':':
+		shorthand, ok := r.markup(key)
+		if !ok {
+			return nil, 0, markupError(src, start+1, "content type %q has no shorthand form", key)
+		}
+		var value strings.Builder
+		for pos++; ; pos++ {
+			if pos >= len(src) {
+				return nil, 0, markupError(src, start, "unterminated tag")
+			}
+			if src[pos] == ']' {
+				break
+			}
+			if src[pos] == '\\' {
+				if pos+1 >= len(src) || !isMarkupEscape(src[pos+1]) {
+					return nil, 0, markupError(src, pos, "invalid escape")
+				}
+				pos++
+			}
+			value.WriteByte(src[pos])
+		}
+		content, err := shorthand.Parse(value.String())
+		if err != nil {
+			return nil, 0, markupError(src, start, "invalid %s: %v", key, err)
+		}
+		return content, pos + 1, nil
+	case '{':
+		decoder := json.NewDecoder(strings.NewReader(src[pos:]))
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			offset := pos
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) && syntaxErr.Offset > 0 {
+				// Offset 为出错字符之后的位置
+				offset += int(syntaxErr.Offset) - 1
+			}
+			return nil, 0, markupError(src, min(offset, len(src)), "invalid %s: %v", key, err)
+		}
+		pos += int(decoder.InputOffset())
+		if pos >= len(src) || src[pos] != ']' {
+			return nil, 0, markupError(src, min(pos, len(src)), "expected ] after %s", key)
+		}
+		content, err := r.DecodeContent(key, raw)
+		if err != nil {
+			return nil, 0, markupError(src, start, "invalid %s: %v", key, err)
+		}
+		return content, pos + 1, nil
+	}
+	return nil, 0, markupError(src, pos, "expected : or { after content type %q", key)
+}
+
+var markupEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`)
+
+// FormatMarkup 使用默认注册表将消息内容格式化为标记文本
+func FormatMarkup(contents Contents) (string, error) {
+	return DefaultContentRegistry.FormatMarkup(contents)
+}
+
+// FormatMarkup 将消息内容格式化为标记文本，能用简写形式无损表示时使用简写形式
+func (r *ContentRegistry) FormatMarkup(contents Contents) (string, error) {
+	var builder strings.Builder
+	for _, content := range contents {
+		if text, ok := contentText(content); ok {
+			builder.WriteString(markupEscaper.Replace(text))
+			continue
+		}
+		if unknown, ok := dereference(content).(ContentUnknown); ok {
+			value := strings.TrimSpace(unknown.Value)
+			if !strings.HasPrefix(value, "{") || !json.Valid([]byte(value)) {
+				return "", fmt.Errorf("markup: content type %q is not a JSON object", unknown.Type)
+			}
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, []byte(value)); err != nil {
+				return "", err
+			}
+			builder.WriteString("[" + unknown.Type + compact.String() + "]")
+			continue
+		}
+		key, ok := r.KeyOf(content)
+		if !ok {
+			return "", fmt.Errorf("markup: unknown type %T", content)
+		}
+		if shorthand, ok := r.markup(key); ok {
+			if value, ok := shorthand.Format(dereference(content).(Content)); ok {
+				builder.WriteString("[" + key + ":" + markupEscaper.Replace(value) + "]")
+				continue
+			}
+		}
+		data, err := r.encodeContent(content)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString("[" + key + string(data) + "]")
+	}
+	return builder.String(), nil
+}
+
+// 简写形式的资源为 URL，协议部分作为 Scheme
+func parseMarkupResource(value string) (Resource, error) {
+	scheme, _, ok := strings.Cut(value, ":")
+	if !ok || scheme == "" {
+		return Resource{}, errors.New("resource must be a URL")
+	}
+	return Resource{Scheme: scheme, Body: value}, nil
+}
+
+func formatMarkupResource(resource Resource) (string, bool) {
+	scheme, _, ok := strings.Cut(resource.Body, ":")
+	return resource.Body, ok && resource.PluginID == 0 && scheme == resource.Scheme
+}
+
+func init() {
+	RegisterMarkup("text", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			return ContentText{Text: value}, nil
+		},
+		Format: func(content Content) (string, bool) {
+			return content.(ContentText).Text, true
+		},
+	})
+	RegisterMarkup("at", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			return ContentAt{Uid: value}, nil
+		},
+		Format: func(content Content) (string, bool) {
+			at := content.(ContentAt)
+			return at.Uid, at.User == nil
+		},
+	})
+	RegisterMarkup("image", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			resource, err := parseMarkupResource(value)
+			return ContentImage{Resource: resource}, err
+		},
+		Format: func(content Content) (string, bool) {
+			image := content.(ContentImage)
+			value, ok := formatMarkupResource(image.Resource)
+			return value, ok && image.Summary == ""
+		},
+	})
+	RegisterMarkup("reply", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			return ContentReply{MessageID: value}, nil
+		},
+		Format: func(content Content) (string, bool) {
+			reply := content.(ContentReply)
+			return reply.MessageID, reply.Summary == ""
+		},
+	})
+	RegisterMarkup("face", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			return ContentFace{ID: value}, nil
+		},
+		Format: func(content Content) (string, bool) {
+			face := content.(ContentFace)
+			return face.ID, face.Name == "" && face.Resource == nil
+		},
+	})
+	RegisterMarkup("file", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			resource, err := parseMarkupResource(value)
+			return ContentFile{Resource: resource}, err
+		},
+		Format: func(content Content) (string, bool) {
+			file := content.(ContentFile)
+			value, ok := formatMarkupResource(file.Resource)
+			return value, ok && file.Name == "" && file.Size == 0
+		},
+	})
+	RegisterMarkup("audio", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			resource, err := parseMarkupResource(value)
+			return ContentAudio{Resource: resource}, err
+		},
+		Format: func(content Content) (string, bool) {
+			audio := content.(ContentAudio)
+			value, ok := formatMarkupResource(audio.Resource)
+			return value, ok && audio.Duration == 0
+		},
+	})
+	RegisterMarkup("video", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			resource, err := parseMarkupResource(value)
+			return ContentVideo{Resource: resource}, err
+		},
+		Format: func(content Content) (string, bool) {
+			video := content.(ContentVideo)
+			value, ok := formatMarkupResource(video.Resource)
+			return value, ok && video.Cover == nil && video.Duration == 0 && video.Summary == ""
+		},
+	})
+	RegisterMarkup("location", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			lat, lng, ok := strings.Cut(value, ",")
+			if !ok {
+				return nil, errors.New("location must be lat,lng")
+			}
+			latitude, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+			if err != nil {
+				return nil, err
+			}
+			longitude, err := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+			if err != nil {
+				return nil, err
+			}
+			return ContentLocation{Latitude: latitude, Longitude: longitude}, nil
+		},
+		Format: func(content Content) (string, bool) {
+			location := content.(ContentLocation)
+			value := strconv.FormatFloat(location.Latitude, 'g', -1, 64) + "," +
+				strconv.FormatFloat(location.Longitude, 'g', -1, 64)
+			return value, location.Name == "" && location.Address == ""
+		},
+	})
+	RegisterMarkup("link", MarkupShorthand{
+		Parse: func(value string) (Content, error) {
+			return ContentLinkCard{URL: value}, nil
+		},
+		Format: func(content Content) (string, bool) {
+			link := content.(ContentLinkCard)
+			return link.URL, link.Title == "" && link.Description == "" && link.Image == nil
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试解析简写形式与转义
+func TestParseMarkup(t *testing.T) {
+	contents, err := ParseMarkup(`Hello [at:123] see [image:file:///tmp/x.png] \[not a tag\] \\`)
+	assert.NoError(t, err)
+	assert.Equal(t, Contents{
+		ContentText{Text: "Hello "},
+		ContentAt{Uid: "123"},
+		ContentText{Text: " see "},
+		ContentImage{Resource: Resource{Scheme: "file", Body: "file:///tmp/x.png"}},
+		ContentText{Text: ` [not a tag] \`},
+	}, contents)
+
+	contents, err = ParseMarkup(`[reply:m1][location:31.5, 121][link:https://example.com/a\]b]`)
+	assert.NoError(t, err)
+	assert.Equal(t, Contents{
+		ContentReply{MessageID: "m1"},
+		ContentLocation{Latitude: 31.5, Longitude: 121},
+		ContentLinkCard{URL: "https://example.com/a]b"},
+	}, contents)
+
+	// 通用形式支持所有注册类型，未注册的类型保留原始数据
+	contents, err = ParseMarkup(`[face{"id":"14","name":"微笑"}][custom{"x": [1, 2]}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, Contents{
+		ContentFace{ID: "14", Name: "微笑"},
+		ContentUnknown{Type: "custom", Value: `{"x": [1, 2]}`},
+	}, contents)
+
+	contents, err = ParseMarkup("")
+	assert.NoError(t, err)
+	assert.Empty(t, contents)
+}
+
+// 测试格式化后再解析不丢失信息
+func TestMarkupRoundTrip(t *testing.T) {
+	resource := Resource{Scheme: "https", Body: "https://example.com/x.png"}
+	contents := Contents{
+		ContentText{Text: "a [b] \\ c\n"},
+		ContentAt{Uid: "u]1"},
+		ContentAt{Uid: "u2", User: &User{Id: "u2", Name: "Bob"}},
+		ContentImage{Resource: resource},
+		ContentImage{Resource: resource, Summary: "cat"},
+		ContentImage{Resource: Resource{PluginID: 2, Scheme: "qq", Body: "abc"}},
+		ContentReply{MessageID: "m1"},
+		ContentFace{ID: "14", Name: "微笑"},
+		ContentFile{Resource: resource, Name: "a.pdf", Size: 3},
+		ContentAudio{Resource: resource},
+		ContentVideo{Resource: resource, Duration: time.Second},
+		ContentLocation{Latitude: 1.25, Longitude: -3},
+		ContentLinkCard{URL: "https://example.com", Title: "Example"},
+		ContentForward{Messages: []Message{{ID: "m2", Content: Contents{ContentText{Text: "nested"}}}}},
+		ContentUnknown{Type: "custom", Value: `{"x":1}`},
+		&ContentText{Text: "end"},
+	}
+	markup, err := FormatMarkup(contents)
+	assert.NoError(t, err)
+	assert.Contains(t, markup, `a \[b\] \\ c`)
+	assert.Contains(t, markup, `[at:u\]1]`)
+	assert.Contains(t, markup, `[image:https://example.com/x.png]`)
+	assert.Contains(t, markup, `[location:1.25,-3]`)
+	assert.Contains(t, markup, `[custom{"x":1}]`)
+
+	parsed, err := ParseMarkup(markup)
+	assert.NoError(t, err)
+	assert.True(t, contents.Equal(parsed), "%s", markup)
+
+	_, err = FormatMarkup(Contents{ContentUnknown{Type: "raw", Value: "plain"}})
+	assert.Error(t, err)
+}
+
+// 测试错误信息中的位置
+func TestParseMarkupErrors(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"hello [at:1", "markup: line 1, column 7: unterminated tag"},
+		{"ok\nbad ] here", "markup: line 2, column 5: unexpected ]"},
+		{`tail \`, "markup: line 1, column 6: invalid escape"},
+		{`\n`, "markup: line 1, column 1: invalid escape"},
+		{"[]", "markup: line 1, column 2: missing content type"},
+		{"[at 1]", `markup: line 1, column 4: expected : or { after content type "at"`},
+		{"[forward:x]", `markup: line 1, column 2: content type "forward" has no shorthand form`},
+		{"你好[image:nope]", "markup: line 1, column 3: invalid image: resource must be a URL"},
+		{`[face{"id":}]`, "markup: line 1, column 12: invalid face: invalid character '}' looking for beginning of value"},
+		{`[face{"id":"1"} ]`, "markup: line 1, column 16: expected ] after face"},
+		{`[location:1]`, "markup: line 1, column 1: invalid location: location must be lat,lng"},
+	}
+	for _, item := range cases {
+		_, err := ParseMarkup(item.src)
+		assert.EqualError(t, err, item.err, item.src)
+		var markupErr *MarkupError
+		assert.True(t, errors.As(err, &markupErr))
+	}
+}
@@ -39,11 +39,20 @@ func (p *Packet) UnmarshalJSON(data []byte) error {
 	p.Type = jp.Type
 	switch p.Type {
 	case PacketTypeEvent:
-		var e Event
-		if err := json.Unmarshal(jp.Data, &e); err != nil {
+		// 已注册的事件类型按 PacketEvent 解码，其余保持为 Event，数据不是 JSON 对象时保留为 EventUnknown
+		var msg jsonPacketMessage
+		if err := json.Unmarshal(jp.Data, &msg); err != nil {
 			return err
 		}
-		p.Data = &e
+		if _, ok := LookupEvent(msg.Type); !ok {
+			var e Event
+			if err := json.Unmarshal(jp.Data, &e); err == nil {
+				p.Data = &e
+				break
+			}
+		}
+		event := decodePacketEvent(msg)
+		p.Data = &event
 	case PacketTypeCall:
 		var c PacketCall
 		if err := json.Unmarshal(jp.Data, &c); err != nil {
@@ -1,16 +1,152 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
 )
 
 type EventType string
 
 var (
-	EventTypeMessage EventType = "message"
-	EventTypeEvent   EventType = "event"
+	EventTypeMessage         EventType = "message"
+	EventTypeEvent           EventType = "event"
+	EventTypeMessageEdited   EventType = "message_edited"   // 消息被编辑
+	EventTypeMessageRecalled EventType = "message_recalled" // 消息被撤回或删除
+	EventTypeReactionAdded   EventType = "reaction_added"   // 添加表情回应
+	EventTypeReactionRemoved EventType = "reaction_removed" // 移除表情回应
+	EventTypeReadReceipt     EventType = "read_receipt"     // 已读回执
+
+	EventTypeMemberJoined       EventType = "member_joined"        // 成员加入
+	EventTypeMemberLeft         EventType = "member_left"          // 成员退出或被移出
+	EventTypeMemberRoleChanged  EventType = "member_role_changed"  // 成员角色变更
+	EventTypeGuildCreated       EventType = "guild_created"        // 群聊创建
+	EventTypeGuildRenamed       EventType = "guild_renamed"        // 群聊改名
+	EventTypeGuildAvatarChanged EventType = "guild_avatar_changed" // 群聊头像变更
+	EventTypeBotAdded           EventType = "bot_added"            // 机器人被加入群聊
+	EventTypeBotRemoved         EventType = "bot_removed"          // 机器人被移出群聊
 )
 
+// 事件类型注册表
+var eventRegistry = struct {
+	mu    sync.RWMutex
+	types map[EventType]reflect.Type
+	keys  map[reflect.Type]EventType
+}{
+	types: make(map[EventType]reflect.Type),
+	keys:  make(map[reflect.Type]EventType),
+}
+
+// RegisterEvent 注册事件类型，PacketEvent 解码时按类型键解码为对应的结构体，注册失败时 panic
+//
+// 类型键格式与内容类型键相同，可以用 . 分隔命名空间，例如 qq.poke
+func RegisterEvent(key EventType, typeOf reflect.Type) {
+	if err := registerEvent(key, typeOf); err != nil {
+		panic(err)
+	}
+}
+
+func registerEvent(key EventType, typeOf reflect.Type) error {
+	if !validContentKey(string(key)) {
+		return fmt.Errorf("invalid event key %q", key)
+	}
+	if typeOf != nil && typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	if typeOf == nil || typeOf.Kind() != reflect.Struct {
+		return fmt.Errorf("type %s of %s is not a struct", typeOf, key)
+	}
+	eventRegistry.mu.Lock()
+	defer eventRegistry.mu.Unlock()
+	if _, ok := eventRegistry.types[key]; ok {
+		return fmt.Errorf("duplicate key %s", key)
+	}
+	if old, ok := eventRegistry.keys[typeOf]; ok {
+		return fmt.Errorf("type %s already registered as %s", typeOf, old)
+	}
+	if _, ok := packetKinds[typeOf]; ok {
+		return fmt.Errorf("type %s is not an event", typeOf)
+	}
+	eventRegistry.types[key] = typeOf
+	eventRegistry.keys[typeOf] = key
+	return nil
+}
+
+// LookupEvent 返回事件类型键对应的 Go 类型
+func LookupEvent(key EventType) (reflect.Type, bool) {
+	eventRegistry.mu.RLock()
+	defer eventRegistry.mu.RUnlock()
+	typeOf, ok := eventRegistry.types[key]
+	return typeOf, ok
+}
+
+// EventKeyOf 返回事件数据的类型键，data 可以是值或指针
+func EventKeyOf(data any) (EventType, bool) {
+	typeOf := reflect.TypeOf(data)
+	if typeOf == nil {
+		return "", false
+	}
+	if typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	return eventKey(typeOf)
+}
+
+func eventKey(typeOf reflect.Type) (EventType, bool) {
+	eventRegistry.mu.RLock()
+	defer eventRegistry.mu.RUnlock()
+	key, ok := eventRegistry.keys[typeOf]
+	return key, ok
+}
+
+// EventUnknown 未注册的事件，按原样保留数据以便无损转发
+//
+// 订阅 Event 的处理器与 EventReceiver 仍会收到数据为 JSON 对象的未知事件
+type EventUnknown struct {
+	Type EventType
+	Data json.RawMessage
+}
+
+// Event 将数据按旧格式解码为 Event，数据不是 JSON 对象时返回 false
+func (e EventUnknown) Event() (Event, bool) {
+	event := Event{Type: string(e.Type)}
+	if len(e.Data) == 0 {
+		return event, true
+	}
+	if err := json.Unmarshal(e.Data, &event.Data); err != nil {
+		return Event{}, false
+	}
+	return event, true
+}
+
+// MarshalJSON 输出原始数据，事件类型由所在的 PacketEvent 保存
+func (e EventUnknown) MarshalJSON() ([]byte, error) {
+	if len(e.Data) == 0 {
+		return []byte("null"), nil
+	}
+	return e.Data, nil
+}
+
+func init() {
+	RegisterEvent(EventTypeMessage, reflect.TypeOf((*Message)(nil)))
+	RegisterEvent(EventTypeEvent, reflect.TypeOf((*Event)(nil)))
+	RegisterEvent(EventTypeMessageEdited, reflect.TypeOf((*MessageEdited)(nil)))
+	RegisterEvent(EventTypeMessageRecalled, reflect.TypeOf((*MessageRecalled)(nil)))
+	RegisterEvent(EventTypeReactionAdded, reflect.TypeOf((*ReactionAdded)(nil)))
+	RegisterEvent(EventTypeReactionRemoved, reflect.TypeOf((*ReactionRemoved)(nil)))
+	RegisterEvent(EventTypeReadReceipt, reflect.TypeOf((*ReadReceipt)(nil)))
+	RegisterEvent(EventTypeMemberJoined, reflect.TypeOf((*MemberJoined)(nil)))
+	RegisterEvent(EventTypeMemberLeft, reflect.TypeOf((*MemberLeft)(nil)))
+	RegisterEvent(EventTypeMemberRoleChanged, reflect.TypeOf((*MemberRoleChanged)(nil)))
+	RegisterEvent(EventTypeGuildCreated, reflect.TypeOf((*GuildCreated)(nil)))
+	RegisterEvent(EventTypeGuildRenamed, reflect.TypeOf((*GuildRenamed)(nil)))
+	RegisterEvent(EventTypeGuildAvatarChanged, reflect.TypeOf((*GuildAvatarChanged)(nil)))
+	RegisterEvent(EventTypeBotAdded, reflect.TypeOf((*BotAdded)(nil)))
+	RegisterEvent(EventTypeBotRemoved, reflect.TypeOf((*BotRemoved)(nil)))
+}
+
 type PacketEvent struct {
 	Type EventType `json:"type"`
 	Data any       `json:"data"`
@@ -26,22 +162,22 @@ func (p *PacketEvent) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return err
 	}
-	p.Type = msg.Type
-	switch msg.Type {
-	case EventTypeMessage:
-		var message Message
-		err := json.Unmarshal(msg.Data, &message)
-		if err != nil {
-			return err
-		}
-		p.Data = &message
-	case EventTypeEvent:
-		var event Event
-		err := json.Unmarshal(msg.Data, &event)
-		if err != nil {
-			return err
-		}
-		p.Data = &event
-	}
+	*p = decodePacketEvent(msg)
 	return nil
 }
+
+// 按类型键解码事件数据，未注册或数据与注册类型不符（含未知字段）时保留为 EventUnknown
+func decodePacketEvent(msg jsonPacketMessage) PacketEvent {
+	unknown := PacketEvent{Type: msg.Type, Data: &EventUnknown{Type: msg.Type, Data: msg.Data}}
+	typeOf, ok := LookupEvent(msg.Type)
+	if !ok {
+		return unknown
+	}
+	value := reflect.New(typeOf)
+	decoder := json.NewDecoder(bytes.NewReader(msg.Data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value.Interface()); err != nil {
+		return unknown
+	}
+	return PacketEvent{Type: msg.Type, Data: value.Interface()}
+}
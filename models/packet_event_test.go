@@ -0,0 +1,227 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试消息编辑、撤回、表情回应与已读回执的编解码
+func TestPacketEventTypes(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	guild := &Guild{Id: "g1"}
+	operator := &GuildMember{User: &User{Id: "u1", Name: "Alice"}}
+	reaction := Reaction{MessageID: "m1", Guild: guild, Operator: operator, Face: ContentFace{ID: "14", Name: "smile"}, Created: now}
+	events := []any{
+		MessageEdited{
+			MessageID: "m1", Guild: guild, Operator: operator,
+			Old: Contents{ContentText{Text: "helo"}}, New: Contents{ContentText{Text: "hello"}, ContentAt{Uid: "u2"}},
+			Edited: now,
+		},
+		MessageRecalled{MessageID: "m1", Guild: guild, Operator: operator, Recalled: now},
+		ReactionAdded{Reaction: reaction},
+		ReactionRemoved{Reaction: reaction},
+		ReadReceipt{MessageID: "m1", Guild: guild, Reader: operator, Read: now},
+	}
+	for _, event := range events {
+		wrap, ok := lookupPacketKind(reflect.TypeOf(event))
+		assert.True(t, ok)
+		packet := wrap(event)
+		packet.Src = "1"
+		data, err := json.Marshal(packet)
+		assert.NoError(t, err)
+
+		var decoded Packet
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.IsType(t, &PacketEvent{}, decoded.Data)
+		assert.Equal(t, packet.Data.(*PacketEvent).Type, decoded.Data.(*PacketEvent).Type)
+		value, err := UnwrapPacket(decoded)
+		assert.NoError(t, err)
+		assert.Equal(t, event, value)
+	}
+}
+
+// 测试未知事件类型保持为 Event
+func TestPacketEventUnknown(t *testing.T) {
+	var packet Packet
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"event","data":{"type":"poke","data":{"user":"u1"}}}`), &packet))
+	assert.Equal(t, &Event{Type: "poke", Data: map[string]any{"user": "u1"}}, packet.Data)
+
+	// 数据不是 JSON 对象时保留原始数据，并按原样编码
+	raw := `{"src":"0","dest":"1","type":"event","data":{"type":"custom","data":[1,2]}}`
+	packet = Packet{}
+	assert.NoError(t, json.Unmarshal([]byte(raw), &packet))
+	assert.Equal(t, &PacketEvent{Type: "custom", Data: &EventUnknown{Type: "custom", Data: json.RawMessage(`[1,2]`)}}, packet.Data)
+	_, ok := packet.Data.(*PacketEvent).Data.(*EventUnknown).Event()
+	assert.False(t, ok)
+	encoded, err := json.Marshal(packet)
+	assert.NoError(t, err)
+	assert.JSONEq(t, raw, string(encoded))
+
+	// 类型键已注册但数据与注册类型不符时，两种解码方式都保留原始数据
+	for _, data := range []string{`{"member":"u1"}`, `{"user_id":"u1"}`, `[1,2]`} {
+		raw := `{"type":"member_joined","data":` + data + `}`
+		expected := &PacketEvent{Type: EventTypeMemberJoined, Data: &EventUnknown{Type: EventTypeMemberJoined, Data: json.RawMessage(data)}}
+		packet = Packet{}
+		assert.NoError(t, json.Unmarshal([]byte(`{"type":"event","data":`+raw+`}`), &packet))
+		assert.Equal(t, expected, packet.Data)
+		var event PacketEvent
+		assert.NoError(t, json.Unmarshal([]byte(raw), &event))
+		assert.Equal(t, expected, &event)
+	}
+
+	var event PacketEvent
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"reaction_added","data":{"id":"m1","face":{"id":"1"}}}`), &event))
+	assert.Equal(t, EventTypeReactionAdded, event.Type)
+	assert.Equal(t, "1", event.Data.(*ReactionAdded).Face.ID)
+
+	// 未注册的事件保留原始数据，可以原样编码转发
+	raw = `{"type":"test.unknown","data":{"b":[1,2],"a":"x"}}`
+	assert.NoError(t, json.Unmarshal([]byte(raw), &event))
+	assert.Equal(t, &EventUnknown{Type: "test.unknown", Data: json.RawMessage(`{"b":[1,2],"a":"x"}`)}, event.Data)
+	legacy, ok := event.Data.(*EventUnknown).Event()
+	assert.True(t, ok)
+	assert.Equal(t, Event{Type: "test.unknown", Data: map[string]any{"b": []any{1.0, 2.0}, "a": "x"}}, legacy)
+	encoded, err = json.Marshal(&event)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, string(encoded))
+
+	value, err := UnwrapPacket(Packet{Type: PacketTypeEvent, Data: &event})
+	assert.NoError(t, err)
+	assert.Equal(t, EventUnknown{Type: "test.unknown", Data: json.RawMessage(`{"b":[1,2],"a":"x"}`)}, value)
+	wrap, ok := lookupPacketKind(reflect.TypeOf(value))
+	assert.True(t, ok)
+	encoded, err = json.Marshal(wrap(value).Data)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, string(encoded))
+}
+
+type testPoke struct {
+	Target string `json:"target"`
+}
+
+// 测试注册自定义事件类型
+func TestRegisterEvent(t *testing.T) {
+	RegisterEvent("test.poke", reflect.TypeOf((*testPoke)(nil)))
+	t.Cleanup(func() {
+		unregisterEvent("test.poke")
+	})
+	typeOf, ok := LookupEvent("test.poke")
+	assert.True(t, ok)
+	assert.Equal(t, reflect.TypeOf(testPoke{}), typeOf)
+	key, ok := EventKeyOf(&testPoke{})
+	assert.True(t, ok)
+	assert.Equal(t, EventType("test.poke"), key)
+
+	var packet Packet
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"event","data":{"type":"test.poke","data":{"target":"u1"}}}`), &packet))
+	assert.Equal(t, &PacketEvent{Type: "test.poke", Data: &testPoke{Target: "u1"}}, packet.Data)
+	value, err := UnwrapPacket(packet)
+	assert.NoError(t, err)
+	assert.Equal(t, testPoke{Target: "u1"}, value)
+
+	wrap, ok := lookupPacketKind(reflect.TypeOf(testPoke{}))
+	assert.True(t, ok)
+	assert.Equal(t, &PacketEvent{Type: "test.poke", Data: &testPoke{Target: "u2"}}, wrap(testPoke{Target: "u2"}).Data)
+
+	assert.Panics(t, func() {
+		RegisterEvent("test.poke", reflect.TypeOf(Event{}))
+	})
+	assert.EqualError(t, registerEvent("test.poke2", reflect.TypeOf(testPoke{})), "type models.testPoke already registered as test.poke")
+	assert.Error(t, registerEvent("Test", reflect.TypeOf(struct{}{})))
+	assert.Error(t, registerEvent("test.number", reflect.TypeOf(0)))
+	assert.Error(t, registerEvent("test.call", reflect.TypeOf(CallRequest{})))
+	assert.Error(t, registerEvent("test.unknown", reflect.TypeOf(EventUnknown{})))
+}
+
+// 测试群聊与成员事件的编解码
+func TestGuildEventTypes(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	guild := &Guild{Id: "g1", Name: "new"}
+	member := &GuildMember{User: &User{Id: "u2", Name: "Bob"}, GuildRole: []string{"admin"}}
+	operator := &GuildMember{User: &User{Id: "u1", Name: "Alice"}, GuildRole: []string{"owner"}}
+	events := []any{
+		MemberJoined{Guild: guild, Member: member, Inviter: operator, Joined: now},
+		MemberLeft{Guild: guild, Member: member, Operator: operator, Kicked: true, Left: now},
+		MemberRoleChanged{Guild: guild, Member: member, Operator: operator, Old: []string{"member"}, New: []string{"admin"}, Changed: now},
+		GuildCreated{Guild: guild, Operator: operator, Created: now},
+		GuildRenamed{Guild: guild, Operator: operator, OldName: "old", Renamed: now},
+		GuildAvatarChanged{Guild: guild, Operator: operator, OldAvatar: Resource{Scheme: "https", Body: "https://example.com/a.png"}, Changed: now},
+		BotAdded{Guild: guild, Bot: member, Operator: operator, Added: now},
+		BotRemoved{Guild: guild, Bot: member, Removed: now},
+	}
+	for _, event := range events {
+		wrap, ok := lookupPacketKind(reflect.TypeOf(event))
+		assert.True(t, ok)
+		packet := wrap(event)
+		data, err := json.Marshal(packet)
+		assert.NoError(t, err)
+		var decoded Packet
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		value, err := UnwrapPacket(decoded)
+		assert.NoError(t, err)
+		assert.Equal(t, event, value)
+	}
+}
+
+// 测试角色变更的差异
+func TestMemberRoleChanged(t *testing.T) {
+	event := MemberRoleChanged{Old: []string{"member", "vip"}, New: []string{"vip", "admin"}}
+	assert.Equal(t, []string{"admin"}, event.Added())
+	assert.Equal(t, []string{"member"}, event.Removed())
+	assert.Empty(t, MemberRoleChanged{Old: []string{"a"}, New: []string{"a"}}.Added())
+}
+
+// 从注册表中移除事件类型，仅用于测试
+func unregisterEvent(key EventType) {
+	eventRegistry.mu.Lock()
+	defer eventRegistry.mu.Unlock()
+	if typeOf, ok := eventRegistry.types[key]; ok {
+		delete(eventRegistry.keys, typeOf)
+		delete(eventRegistry.types, key)
+	}
+}
+
+// 测试未知事件同时分发给 EventUnknown 与 Event 的订阅者
+func TestSubscribeEventUnknown(t *testing.T) {
+	bus := PluginBus{Subscriptions: NewSubscriptions()}
+	var unknowns []EventUnknown
+	var events []Event
+	assert.NoError(t, Subscribe(bus, func(ctx context.Context, packet WithSrcPacket[EventUnknown]) {
+		unknowns = append(unknowns, packet.Data)
+	}))
+	assert.NoError(t, Subscribe(bus, func(ctx context.Context, packet WithSrcPacket[Event]) {
+		events = append(events, packet.Data)
+	}))
+
+	poke := EventUnknown{Type: "poke", Data: json.RawMessage(`{"user":"u1"}`)}
+	count, err := bus.Subscriptions.Dispatch(context.Background(), Packet{
+		Type: PacketTypeEvent,
+		Data: &PacketEvent{Type: poke.Type, Data: &poke},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	count, err = bus.Subscriptions.Dispatch(context.Background(), Packet{
+		Type: PacketTypeEvent,
+		Data: json.RawMessage(`{"type":"custom","data":[1,2]}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	// 数据为 JSON 对象的未注册事件按旧格式解码为 Event
+	count, err = bus.Subscriptions.Dispatch(context.Background(), Packet{
+		Type: PacketTypeEvent,
+		Data: json.RawMessage(`{"type":"wave","data":{"user":"u2"}}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.Equal(t, []EventUnknown{poke, {Type: "custom", Data: json.RawMessage(`[1,2]`)}}, unknowns)
+	assert.Equal(t, []Event{
+		{Type: "poke", Data: map[string]any{"user": "u1"}},
+		{Type: "wave", Data: map[string]any{"user": "u2"}},
+	}, events)
+}
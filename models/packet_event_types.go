@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 type Message struct {
 	ID    string       `json:"id"`
@@ -19,3 +22,135 @@ type Event struct {
 	Type string         `json:"type"`
 	Data map[string]any `json:"data,omitempty"`
 }
+
+// MessageEdited 消息被编辑
+type MessageEdited struct {
+	MessageID string       `json:"id"`
+	Guild     *Guild       `json:"guild"`
+	Operator  *GuildMember `json:"operator,omitempty"` // 编辑者
+	Old       Contents     `json:"old"`
+	New       Contents     `json:"new"`
+	Edited    time.Time    `json:"edited"`
+}
+
+// MessageRecalled 消息被撤回或删除
+type MessageRecalled struct {
+	MessageID string       `json:"id"`
+	Guild     *Guild       `json:"guild"`
+	Operator  *GuildMember `json:"operator,omitempty"` // 撤回者，可能不是消息作者
+	Recalled  time.Time    `json:"recalled"`
+}
+
+// Reaction 对消息的表情回应
+type Reaction struct {
+	MessageID string       `json:"id"`
+	Guild     *Guild       `json:"guild"`
+	Operator  *GuildMember `json:"operator,omitempty"`
+	Face      ContentFace  `json:"face"`
+	Created   time.Time    `json:"created"`
+}
+
+// ReactionAdded 添加表情回应
+type ReactionAdded struct {
+	Reaction
+}
+
+// ReactionRemoved 移除表情回应
+type ReactionRemoved struct {
+	Reaction
+}
+
+// ReadReceipt 已读回执，表示 Reader 已读到 MessageID 为止的消息
+type ReadReceipt struct {
+	MessageID string       `json:"id"`
+	Guild     *Guild       `json:"guild"`
+	Reader    *GuildMember `json:"reader"`
+	Read      time.Time    `json:"read"`
+}
+
+// MemberJoined 成员加入群聊
+type MemberJoined struct {
+	Guild   *Guild       `json:"guild"`
+	Member  *GuildMember `json:"member"`
+	Inviter *GuildMember `json:"inviter,omitempty"` // 邀请者，主动加入时为空
+	Joined  time.Time    `json:"joined"`
+}
+
+// MemberLeft 成员退出或被移出群聊
+type MemberLeft struct {
+	Guild    *Guild       `json:"guild"`
+	Member   *GuildMember `json:"member"`
+	Operator *GuildMember `json:"operator,omitempty"` // 移出成员的管理者，主动退出时为空
+	Kicked   bool         `json:"kicked"`
+	Left     time.Time    `json:"left"`
+}
+
+// MemberRoleChanged 成员角色变更
+type MemberRoleChanged struct {
+	Guild    *Guild       `json:"guild"`
+	Member   *GuildMember `json:"member"` // 变更后的成员信息
+	Operator *GuildMember `json:"operator,omitempty"`
+	Old      []string     `json:"old"`
+	New      []string     `json:"new"`
+	Changed  time.Time    `json:"changed"`
+}
+
+// Added 返回新增的角色
+func (e MemberRoleChanged) Added() []string {
+	return roleDiff(e.New, e.Old)
+}
+
+// Removed 返回被移除的角色
+func (e MemberRoleChanged) Removed() []string {
+	return roleDiff(e.Old, e.New)
+}
+
+// 返回在 a 中但不在 b 中的角色
+func roleDiff(a, b []string) []string {
+	var result []string
+	for _, role := range a {
+		if !slices.Contains(b, role) {
+			result = append(result, role)
+		}
+	}
+	return result
+}
+
+// GuildCreated 群聊创建
+type GuildCreated struct {
+	Guild    *Guild       `json:"guild"`
+	Operator *GuildMember `json:"operator,omitempty"` // 创建者
+	Created  time.Time    `json:"created"`
+}
+
+// GuildRenamed 群聊改名，Guild 为改名后的信息
+type GuildRenamed struct {
+	Guild    *Guild       `json:"guild"`
+	Operator *GuildMember `json:"operator,omitempty"`
+	OldName  string       `json:"old_name"`
+	Renamed  time.Time    `json:"renamed"`
+}
+
+// GuildAvatarChanged 群聊头像变更，Guild 为变更后的信息
+type GuildAvatarChanged struct {
+	Guild     *Guild       `json:"guild"`
+	Operator  *GuildMember `json:"operator,omitempty"`
+	OldAvatar Resource     `json:"old_avatar"`
+	Changed   time.Time    `json:"changed"`
+}
+
+// BotAdded 机器人被加入群聊
+type BotAdded struct {
+	Guild    *Guild       `json:"guild"`
+	Bot      *GuildMember `json:"bot"`
+	Operator *GuildMember `json:"operator,omitempty"` // 邀请者
+	Added    time.Time    `json:"added"`
+}
+
+// BotRemoved 机器人被移出群聊或群聊解散
+type BotRemoved struct {
+	Guild    *Guild       `json:"guild"`
+	Bot      *GuildMember `json:"bot"`
+	Operator *GuildMember `json:"operator,omitempty"` // 移出者，群聊解散时可能为空
+	Removed  time.Time    `json:"removed"`
+}
@@ -0,0 +1,328 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RenderFunc 渲染单个内容，content 为值类型
+type RenderFunc func(renderer *Renderer, content Content) string
+
+// Renderer 将消息内容渲染为特定格式的文本
+//
+// 每种内容类型可以设置渲染函数，未设置的类型渲染为转义后的 String()
+type Renderer struct {
+	mu     sync.RWMutex
+	escape func(text string) string
+	hooks  map[reflect.Type]RenderFunc
+}
+
+// NewRenderer 创建渲染器，escape 用于转义文本，为 nil 时不转义
+func NewRenderer(escape func(text string) string) *Renderer {
+	if escape == nil {
+		escape = func(text string) string { return text }
+	}
+	return &Renderer{
+		escape: escape,
+		hooks:  make(map[reflect.Type]RenderFunc),
+	}
+}
+
+// RenderHook 设置类型 C 的渲染函数，指针类型的内容同样使用该函数
+func RenderHook[C Content](renderer *Renderer, hook func(renderer *Renderer, content C) string) {
+	renderer.mu.Lock()
+	defer renderer.mu.Unlock()
+	renderer.hooks[reflect.TypeFor[C]()] = func(renderer *Renderer, content Content) string {
+		return hook(renderer, content.(C))
+	}
+}
+
+// Clone 复制渲染器，修改副本不影响原渲染器
+func (r *Renderer) Clone() *Renderer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clone := NewRenderer(r.escape)
+	for typeOf, hook := range r.hooks {
+		clone.hooks[typeOf] = hook
+	}
+	return clone
+}
+
+// Escape 转义文本
+func (r *Renderer) Escape(text string) string {
+	return r.escape(text)
+}
+
+// Render 渲染消息内容
+func (r *Renderer) Render(contents Contents) string {
+	var builder strings.Builder
+	for _, content := range contents {
+		builder.WriteString(r.RenderContent(content))
+	}
+	return builder.String()
+}
+
+// RenderContent 渲染单个内容
+func (r *Renderer) RenderContent(content Content) string {
+	value := dereference(content).(Content)
+	r.mu.RLock()
+	hook, ok := r.hooks[reflect.TypeOf(value)]
+	r.mu.RUnlock()
+	if ok {
+		return hook(r, value)
+	}
+	if text, ok := contentText(value); ok {
+		return r.escape(text)
+	}
+	return r.escape(value.String())
+}
+
+// 消息作者的显示名称
+func authorName(member *GuildMember) string {
+	switch {
+	case member == nil || member.User == nil:
+		return ""
+	case member.GuildName != "":
+		return member.GuildName
+	case member.User.Name != "":
+		return member.User.Name
+	}
+	return member.User.Id
+}
+
+// 提及的显示名称
+func mentionName(at ContentAt) string {
+	if at.User != nil && at.User.Name != "" {
+		return at.User.Name
+	}
+	return at.Uid
+}
+
+func locationName(location ContentLocation) string {
+	if location.Name != "" {
+		return location.Name
+	}
+	return strconv.FormatFloat(location.Latitude, 'g', -1, 64) + "," +
+		strconv.FormatFloat(location.Longitude, 'g', -1, 64)
+}
+
+func locationURL(location ContentLocation) string {
+	return "geo:" + strconv.FormatFloat(location.Latitude, 'g', -1, 64) + "," +
+		strconv.FormatFloat(location.Longitude, 'g', -1, 64)
+}
+
+// 非空时返回 value，否则返回 fallback
+func orDefault(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
+
+// 每行前加上前缀
+func prefixLines(text, prefix string) string {
+	return prefix + strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "\n"+prefix)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`~`, `\~`, `<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`,
+)
+
+// 链接地址中的括号需要转义，包含空白时使用 <地址> 形式
+var markdownURLEscaper = strings.NewReplacer(`(`, `\(`, `)`, `\)`, `<`, `\<`, `>`, `\>`)
+
+func markdownURL(url string) string {
+	url = markdownURLEscaper.Replace(url)
+	if strings.ContainsAny(url, " \t") {
+		return "<" + url + ">"
+	}
+	return url
+}
+
+// MarkdownRenderer Markdown 渲染器
+var MarkdownRenderer = newMarkdownRenderer()
+
+func newMarkdownRenderer() *Renderer {
+	renderer := NewRenderer(markdownEscaper.Replace)
+	link := func(renderer *Renderer, title, url string) string {
+		return "[" + renderer.Escape(title) + "](" + markdownURL(safeURL(url)) + ")"
+	}
+	RenderHook(renderer, func(renderer *Renderer, at ContentAt) string {
+		return "@" + renderer.Escape(mentionName(at))
+	})
+	RenderHook(renderer, func(renderer *Renderer, image ContentImage) string {
+		return "!" + link(renderer, image.Summary, image.Resource.Body)
+	})
+	RenderHook(renderer, func(renderer *Renderer, reply ContentReply) string {
+		if reply.Summary == "" {
+			return ""
+		}
+		return prefixLines(renderer.Escape(reply.Summary), "> ") + "\n\n"
+	})
+	RenderHook(renderer, func(renderer *Renderer, face ContentFace) string {
+		if face.Name == "" {
+			return renderer.Escape(face.String())
+		}
+		return ":" + renderer.Escape(face.Name) + ":"
+	})
+	RenderHook(renderer, func(renderer *Renderer, file ContentFile) string {
+		return link(renderer, orDefault(file.Name, "file"), file.Resource.Body)
+	})
+	RenderHook(renderer, func(renderer *Renderer, audio ContentAudio) string {
+		return link(renderer, "audio", audio.Resource.Body)
+	})
+	RenderHook(renderer, func(renderer *Renderer, video ContentVideo) string {
+		return link(renderer, orDefault(video.Summary, "video"), video.Resource.Body)
+	})
+	RenderHook(renderer, func(renderer *Renderer, location ContentLocation) string {
+		return link(renderer, locationName(location), locationURL(location))
+	})
+	RenderHook(renderer, func(renderer *Renderer, card ContentLinkCard) string {
+		return link(renderer, orDefault(card.Title, card.URL), card.URL)
+	})
+	RenderHook(renderer, func(renderer *Renderer, forward ContentForward) string {
+		var lines []string
+		for _, message := range forward.Messages {
+			text := renderer.Render(message.Content)
+			if name := authorName(message.Owner); name != "" {
+				text = "**" + renderer.Escape(name) + "**: " + text
+			}
+			lines = append(lines, prefixLines(text, "> "))
+		}
+		return strings.Join(lines, "\n>\n") + "\n\n"
+	})
+	return renderer
+}
+
+// 允许出现在 HTML 链接中的协议
+var safeSchemes = map[string]bool{
+	"http": true, "https": true, "mailto": true, "geo": true, "ftp": true,
+}
+
+// 过滤 javascript: 等不安全的链接
+func safeURL(url string) string {
+	scheme, _, ok := strings.Cut(url, ":")
+	if ok && !strings.ContainsAny(scheme, "/?#") && !safeSchemes[strings.ToLower(strings.TrimSpace(scheme))] {
+		return "#"
+	}
+	return url
+}
+
+func htmlEscape(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// HTMLRenderer HTML 渲染器，所有文本与属性都会被转义
+var HTMLRenderer = newHTMLRenderer()
+
+func newHTMLRenderer() *Renderer {
+	renderer := NewRenderer(htmlEscape)
+	link := func(renderer *Renderer, title, url string) string {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(safeURL(url)), renderer.Escape(title))
+	}
+	RenderHook(renderer, func(renderer *Renderer, at ContentAt) string {
+		return fmt.Sprintf(`<span class="mention" data-uid="%s">@%s</span>`,
+			html.EscapeString(at.Uid), renderer.Escape(mentionName(at)))
+	})
+	RenderHook(renderer, func(renderer *Renderer, image ContentImage) string {
+		return fmt.Sprintf(`<img src="%s" alt="%s">`,
+			html.EscapeString(safeURL(image.Resource.Body)), html.EscapeString(image.Summary))
+	})
+	RenderHook(renderer, func(renderer *Renderer, reply ContentReply) string {
+		if reply.Summary == "" {
+			return ""
+		}
+		return "<blockquote>" + renderer.Escape(reply.Summary) + "</blockquote>"
+	})
+	RenderHook(renderer, func(renderer *Renderer, face ContentFace) string {
+		if face.Name == "" {
+			return renderer.Escape(face.String())
+		}
+		return ":" + renderer.Escape(face.Name) + ":"
+	})
+	RenderHook(renderer, func(renderer *Renderer, file ContentFile) string {
+		return link(renderer, orDefault(file.Name, "file"), file.Resource.Body)
+	})
+	RenderHook(renderer, func(renderer *Renderer, audio ContentAudio) string {
+		return fmt.Sprintf(`<audio src="%s" controls></audio>`, html.EscapeString(safeURL(audio.Resource.Body)))
+	})
+	RenderHook(renderer, func(renderer *Renderer, video ContentVideo) string {
+		return fmt.Sprintf(`<video src="%s" controls></video>`, html.EscapeString(safeURL(video.Resource.Body)))
+	})
+	RenderHook(renderer, func(renderer *Renderer, location ContentLocation) string {
+		return link(renderer, locationName(location), locationURL(location))
+	})
+	RenderHook(renderer, func(renderer *Renderer, card ContentLinkCard) string {
+		return link(renderer, orDefault(card.Title, card.URL), card.URL)
+	})
+	RenderHook(renderer, func(renderer *Renderer, forward ContentForward) string {
+		var builder strings.Builder
+		for _, message := range forward.Messages {
+			builder.WriteString("<blockquote>")
+			if name := authorName(message.Owner); name != "" {
+				builder.WriteString("<b>" + renderer.Escape(name) + "</b>: ")
+			}
+			builder.WriteString(renderer.Render(message.Content))
+			builder.WriteString("</blockquote>")
+		}
+		return builder.String()
+	})
+	return renderer
+}
+
+// PlainRenderer 纯文本渲染器
+var PlainRenderer = newPlainRenderer()
+
+func newPlainRenderer() *Renderer {
+	renderer := NewRenderer(nil)
+	RenderHook(renderer, func(renderer *Renderer, at ContentAt) string {
+		return "@" + mentionName(at)
+	})
+	RenderHook(renderer, func(renderer *Renderer, image ContentImage) string {
+		if image.Summary == "" {
+			return "[image]"
+		}
+		return "[image: " + image.Summary + "]"
+	})
+	RenderHook(renderer, func(renderer *Renderer, reply ContentReply) string {
+		return ""
+	})
+	RenderHook(renderer, func(renderer *Renderer, face ContentFace) string {
+		return "[" + orDefault(face.Name, "face") + "]"
+	})
+	RenderHook(renderer, func(renderer *Renderer, file ContentFile) string {
+		return "[file: " + orDefault(file.Name, file.Resource.Body) + "]"
+	})
+	RenderHook(renderer, func(renderer *Renderer, audio ContentAudio) string {
+		return "[audio]"
+	})
+	RenderHook(renderer, func(renderer *Renderer, video ContentVideo) string {
+		return "[video]"
+	})
+	RenderHook(renderer, func(renderer *Renderer, location ContentLocation) string {
+		return "[location: " + locationName(location) + "]"
+	})
+	RenderHook(renderer, func(renderer *Renderer, card ContentLinkCard) string {
+		if card.Title == "" {
+			return card.URL
+		}
+		return card.Title + " (" + card.URL + ")"
+	})
+	RenderHook(renderer, func(renderer *Renderer, forward ContentForward) string {
+		var lines []string
+		for _, message := range forward.Messages {
+			text := renderer.Render(message.Content)
+			if name := authorName(message.Owner); name != "" {
+				text = name + ": " + text
+			}
+			lines = append(lines, text)
+		}
+		return strings.Join(lines, "\n")
+	})
+	return renderer
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func renderSample() Contents {
+	resource := Resource{Scheme: "https", Body: "https://example.com/cat.png"}
+	return Contents{
+		ContentReply{MessageID: "m0", Summary: "earlier"},
+		ContentText{Text: "Hi *all* <b>\n"},
+		ContentAt{Uid: "u1", User: &User{Id: "u1", Name: "Alice"}},
+		ContentText{Text: " "},
+		ContentImage{Resource: resource, Summary: "cat"},
+		ContentFace{ID: "14", Name: "smile"},
+		ContentFile{Resource: Resource{Scheme: "https", Body: "https://example.com/a b.pdf"}, Name: "a.pdf"},
+		ContentLocation{Latitude: 1.5, Longitude: 2, Name: "home"},
+		ContentLinkCard{URL: "javascript:alert(1)", Title: "evil"},
+	}
+}
+
+// 测试 Markdown 渲染
+func TestMarkdownRenderer(t *testing.T) {
+	assert.Equal(t,
+		"> earlier\n\n"+
+			"Hi \\*all\\* \\<b\\>\n"+
+			"@Alice "+
+			"![cat](https://example.com/cat.png)"+
+			":smile:"+
+			"[a.pdf](<https://example.com/a b.pdf>)"+
+			"[home](geo:1.5,2)"+
+			"[evil](#)",
+		MarkdownRenderer.Render(renderSample()))
+
+	forward := Contents{ContentForward{Messages: []Message{
+		{Owner: &GuildMember{User: &User{Id: "u1", Name: "Alice"}}, Content: Contents{ContentText{Text: "one\ntwo"}}},
+		{Content: Contents{ContentText{Text: "three"}}},
+	}}}
+	assert.Equal(t, "> **Alice**: one\n> two\n>\n> three\n\n", MarkdownRenderer.Render(forward))
+}
+
+// 测试 HTML 渲染的转义与不安全链接
+func TestHTMLRenderer(t *testing.T) {
+	assert.Equal(t,
+		"<blockquote>earlier</blockquote>"+
+			"Hi *all* &lt;b&gt;<br>"+
+			`<span class="mention" data-uid="u1">@Alice</span> `+
+			`<img src="https://example.com/cat.png" alt="cat">`+
+			":smile:"+
+			`<a href="https://example.com/a b.pdf">a.pdf</a>`+
+			`<a href="geo:1.5,2">home</a>`+
+			`<a href="#">evil</a>`,
+		HTMLRenderer.Render(renderSample()))
+	assert.Equal(t, `<img src="&#34;onerror=&#34;x" alt="&lt;script&gt;">`,
+		HTMLRenderer.Render(Contents{ContentImage{Resource: Resource{Body: `"onerror="x`}, Summary: "<script>"}}))
+}
+
+// 测试纯文本渲染
+func TestPlainRenderer(t *testing.T) {
+	assert.Equal(t,
+		"Hi *all* <b>\n@Alice [image: cat][smile][file: a.pdf][location: home]evil (javascript:alert(1))",
+		PlainRenderer.Render(renderSample()))
+}
+
+type customContent struct {
+	Value string
+}
+
+func (c customContent) String() string {
+	return "custom:" + c.Value
+}
+
+// 测试自定义内容的渲染函数
+func TestRenderHook(t *testing.T) {
+	contents := Contents{customContent{Value: "<x>"}, &customContent{Value: "y"}}
+	// 未设置渲染函数时使用转义后的 String()
+	assert.Equal(t, "custom:&lt;x&gt;custom:y", HTMLRenderer.Render(contents))
+
+	renderer := HTMLRenderer.Clone()
+	RenderHook(renderer, func(renderer *Renderer, content customContent) string {
+		return "<code>" + renderer.Escape(content.Value) + "</code>"
+	})
+	assert.Equal(t, "<code>&lt;x&gt;</code><code>y</code>", renderer.Render(contents))
+	// 副本的修改不影响原渲染器
+	assert.Equal(t, "custom:&lt;x&gt;custom:y", HTMLRenderer.Render(contents))
+}
+
+// 测试 Markdown 解析
+func TestParseMarkdown(t *testing.T) {
+	assert.Equal(t, Contents{
+		ContentText{Text: "Hello world *x* "},
+		ContentImage{Resource: Resource{Scheme: "https", Body: "https://example.com/a.png"}, Summary: "alt [1]"},
+		ContentText{Text: " see "},
+		ContentLinkCard{URL: "https://example.com/(x)", Title: "docs"},
+		ContentText{Text: " or "},
+		ContentLinkCard{URL: "https://example.com"},
+		ContentText{Text: " code: a*b [c](d) and [broken"},
+	}, ParseMarkdown("Hello **world** \\*x\\* ![alt \\[1\\]](https://example.com/a.png) see "+
+		"[docs](https://example.com/\\(x\\)) or <https://example.com> code: `a*b [c](d)` and [broken"))
+
+	// 渲染后再解析保留文本、图片与链接
+	contents := Contents{
+		ContentText{Text: "a*b_c [d] <e> #f\\"},
+		ContentImage{Resource: Resource{Scheme: "https", Body: "https://example.com/x y.png"}, Summary: "x]"},
+		ContentLinkCard{URL: "https://example.com/(1)", Title: "one"},
+		ContentLinkCard{URL: "https://example.com/2"},
+	}
+	assert.Equal(t, contents, ParseMarkdown(MarkdownRenderer.Render(contents)))
+
+	// 无标题链接同样过滤不安全的协议并转义地址
+	assert.Equal(t, "[javascript:alert(1)](#)", MarkdownRenderer.Render(Contents{ContentLinkCard{URL: "javascript:alert(1)"}}))
+	assert.Equal(t, "[https://x/a\\>b](https://x/a\\>b)", MarkdownRenderer.Render(Contents{ContentLinkCard{URL: "https://x/a>b"}}))
+	assert.Equal(t, Contents{ContentLinkCard{URL: "https://x/a>b"}}, ParseMarkdown(MarkdownRenderer.Render(Contents{ContentLinkCard{URL: "https://x/a>b"}})))
+
+	// 只移除成对且位于词边界的强调标记
+	assert.Equal(t, Contents{ContentText{Text: "call __init__ now"}}, ParseMarkdown("call __init__ now"))
+	assert.Equal(t, Contents{ContentText{Text: "two words, gone and bold"}}, ParseMarkdown("__two words__, ~~gone~~ and **bold**"))
+	assert.Equal(t, Contents{ContentText{Text: "2**3 and **open and a__b__c"}}, ParseMarkdown("2**3 and **open and a__b__c"))
+	assert.Equal(t, Contents{ContentText{Text: "x ** y"}}, ParseMarkdown("**x `**` y**"))
+	assert.Equal(t, Contents{
+		ContentText{Text: "**a "},
+		ContentLinkCard{URL: "https://x", Title: "b**"},
+	}, ParseMarkdown("**a [b**](https://x)"))
+	assert.Equal(t, Contents{
+		ContentText{Text: "a "},
+		ContentLinkCard{URL: "https://x", Title: "b**"},
+		ContentText{Text: " c"},
+	}, ParseMarkdown("**a [b**](https://x) c**"))
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/greek-milk-bot/core/utils"
@@ -13,27 +16,99 @@ import (
 type Router[T any] struct {
 	defaultTtl uint8 // 默认TTL值
 	routes     *utils.Map[string, *Route[T]]
-	messages   chan RoutePacket[T]
+	lanes      *lanes[T] // 按优先级划分的消息队列
+	classifier func(header RoutePacketHeader, data T) Priority
+	dedupKey   func(header RoutePacketHeader, data T) string
+	seen       *seenSet // 已见去重键，nil 表示未开启去重
 	groups     *utils.Map[string, mapset.Set[string]]
 	// 组-成员映射
 	filter *utils.Map[string, *utils.Map[string, *utils.Array[*Filter[T]]]] // 过滤器
 
+	strategies      *utils.Map[string, AnycastStrategy[T]] // 组-任播策略
+	defaultStrategy AnycastStrategy[T]                     // 默认任播策略
+
+	pending  *utils.Map[string, chan requestResult[T]] // 等待应答的请求
+	sequence atomic.Uint64                             // 请求 ID 序列
+
+	clock     Clock       // 时钟
+	scheduler *scheduler  // 定时发送
+	metrics   MetricsSink // 指标
+	tracer    Tracer      // 链路记录
+
+	retryPolicy RetryPolicy                // 可靠包重试策略
+	deadLetter  func(letter DeadLetter[T]) // 死信处理
+
+	journal       *Journal        // 持久包日志
+	journalCodec  JournalCodec[T] // 持久包编解码
+	replayEntries []JournalEntry  // 等待重新投递的日志记录
+	replayOnce    sync.Once
+
+	rateLimits *utils.Map[rateLimitKey, *tokenBucket] // 限流令牌桶
+
+	synchronous bool // 是否在调用 Flush 的协程中同步投递
+
+	loopDetection bool // 是否拒绝投递到转发栈中已存在的路由
+	maxStackDepth int  // 转发栈最大深度，0 表示不限制
+
+	watchers   *utils.Map[uint64, func(event TopologyEvent)] // 拓扑变化订阅
+	watcherSeq atomic.Uint64
+
+	done chan struct{}
 	once sync.Once
 }
 
-func NewRouter[T any](ttl uint8) *Router[T] {
+// RouterOption 路由器可选配置
+type RouterOption[T any] func(*Router[T])
+
+// WithClock 替换路由器使用的时钟
+func WithClock[T any](clock Clock) RouterOption[T] {
+	return func(r *Router[T]) {
+		r.clock = clock
+	}
+}
+
+// WithLoopDetection 开启环路检测，转发时拒绝投递到转发栈中已存在的路由，
+// maxDepth 大于 0 时转发栈超过该深度的包将被丢弃
+func WithLoopDetection[T any](maxDepth int) RouterOption[T] {
+	return func(r *Router[T]) {
+		r.loopDetection = true
+		r.maxStackDepth = maxDepth
+	}
+}
+
+func NewRouter[T any](ttl uint8, opts ...RouterOption[T]) *Router[T] {
 	if ttl == 0 {
 		ttl = 64
 	}
-	return &Router[T]{
+	r := &Router[T]{
 		defaultTtl: ttl,
-		messages:   make(chan RoutePacket[T], ttl),
+		lanes:      newLanes[T](int(ttl)),
 		filter:     utils.NewMap[string, *utils.Map[string, *utils.Array[*Filter[T]]]](),
 		routes:     utils.NewMap[string, *Route[T]](),
 		groups:     utils.NewMap[string, mapset.Set[string]](),
 
+		strategies:      utils.NewMap[string, AnycastStrategy[T]](),
+		defaultStrategy: NewRoundRobinStrategy[T](),
+
+		pending: utils.NewMap[string, chan requestResult[T]](),
+
+		clock:   SystemClock(),
+		metrics: noopMetrics{},
+		tracer:  noopTracer{},
+
+		retryPolicy: DefaultRetryPolicy,
+		rateLimits:  utils.NewMap[rateLimitKey, *tokenBucket](),
+
+		watchers: utils.NewMap[uint64, func(event TopologyEvent)](),
+
+		done: make(chan struct{}),
 		once: sync.Once{},
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.scheduler = newScheduler(r.clock)
+	return r
 }
 
 type RoutePacketType uint8
@@ -42,11 +117,31 @@ const (
 	RoutePacketTypeUnicast RoutePacketType = iota
 	RoutePacketTypeBroadcast
 	RoutePacketTypeMulticast
+	RoutePacketTypeAnycast
+	RoutePacketTypeReply
 )
 
+func (t RoutePacketType) String() string {
+	switch t {
+	case RoutePacketTypeUnicast:
+		return "unicast"
+	case RoutePacketTypeBroadcast:
+		return "broadcast"
+	case RoutePacketTypeMulticast:
+		return "multicast"
+	case RoutePacketTypeAnycast:
+		return "anycast"
+	case RoutePacketTypeReply:
+		return "reply"
+	}
+	return "unknown"
+}
+
 type RoutePacket[T any] struct {
 	Header RoutePacketHeader
 	Data   T
+
+	delivery *delivery // 持久包的投递计数
 }
 
 type RoutePacketHeader struct {
@@ -55,14 +150,37 @@ type RoutePacketHeader struct {
 	Dest  string
 	Stack []string
 	Ttl   uint8
+	ID    string // 请求 ID，用于关联请求与应答
+
+	Trace    TraceContext // 链路信息
+	Hops     []time.Time  // 与 Stack 对应的每跳发送时间
+	Priority Priority     // 优先级
+
+	IdempotencyKey string // 去重键，相同去重键的包只处理一次
+	Reliable       bool   // 是否需要处理器确认，未确认时重试直到进入死信
+	Durable        bool   // 是否在确认前记录到日志中
+
+	journal uint64 // 日志序号
 }
 
 type Route[T any] struct {
 	name    string
 	router  *Router[T]
-	handler Handler[T]
+	handler ReliableHandler[T]
+
+	groups     mapset.Set[string]          // 该路由加入的组
+	predicates *utils.Array[*Predicate[T]] // 接收广播、组播与任播前的判断条件
+	inflight   atomic.Int64                // 正在处理中的包数量
+}
 
-	groups mapset.Set[string] // 该路由加入的组
+// Name 返回路由名称
+func (r *Route[T]) Name() string {
+	return r.name
+}
+
+// InFlight 返回该路由正在处理中的包数量
+func (r *Route[T]) InFlight() int64 {
+	return r.inflight.Load()
 }
 
 func (r *Router[T]) AddRoute(name string) (*Route[T], error) {
@@ -71,10 +189,13 @@ func (r *Router[T]) AddRoute(name string) (*Route[T], error) {
 		name:    name,
 		handler: nil,
 		groups:  mapset.NewSet[string](),
+
+		predicates: utils.NewArray[*Predicate[T]](),
 	})
 	if loaded {
 		return store, fmt.Errorf("route %s already exists", name)
 	}
+	r.emitTopology(TopologyEvent{Type: TopologyRouteAdded, Route: name})
 	return store, nil
 }
 
@@ -106,6 +227,7 @@ func (r *Router[T]) RemoveRoute(name string) error {
 	for _, group := range item.groups.ToSlice() {
 		item.LeaveGroup(group)
 	}
+	r.emitTopology(TopologyEvent{Type: TopologyRouteRemoved, Route: name})
 	return nil
 }
 
@@ -116,21 +238,32 @@ type (
 
 // 发送单播包
 func (r *Route[T]) Send(dest string, message T) {
-	r.router.messages <- RoutePacket[T]{
+	r.SendContext(context.Background(), dest, message)
+}
+
+// SendContext 发送单播包，ctx 中的链路信息会作为该包的父节点
+func (r *Route[T]) SendContext(ctx context.Context, dest string, message T) {
+	trace, _ := TraceFromContext(ctx)
+	r.router.enqueue(RoutePacket[T]{
 		Header: RoutePacketHeader{
-			Type:  RoutePacketTypeUnicast,
-			Src:   r.name,
-			Dest:  dest,
-			Stack: []string{r.name},
-			Ttl:   r.router.defaultTtl,
+			Type:     RoutePacketTypeUnicast,
+			Src:      r.name,
+			Dest:     dest,
+			Stack:    []string{r.name},
+			Ttl:      r.router.defaultTtl,
+			Trace:    trace,
+			Priority: priorityFromContext(ctx),
+
+			IdempotencyKey: idempotencyKeyFromContext(ctx),
 		},
 		Data: message,
-	}
+	})
 }
 
 // 发送转发包
 func (r *Route[T]) SendForward(dest string, stack *RoutePacketHeader, message T) {
 	if stack.Ttl <= 1 {
+		r.router.metrics.PacketExpired(stack.Type, dest)
 		return // TTL即将耗尽，不再转发
 	}
 
@@ -138,35 +271,61 @@ func (r *Route[T]) SendForward(dest string, stack *RoutePacketHeader, message T)
 	copy(newStack, stack.Stack)
 	newStack = append(newStack, r.name)
 
-	r.router.messages <- RoutePacket[T]{
+	packet := RoutePacket[T]{
 		Header: RoutePacketHeader{
-			Type:  stack.Type,
-			Src:   stack.Src,
-			Dest:  dest,
-			Stack: newStack,
-			Ttl:   stack.Ttl - 1,
+			Type:     stack.Type,
+			Src:      stack.Src,
+			Dest:     dest,
+			Stack:    newStack,
+			Ttl:      stack.Ttl - 1,
+			ID:       stack.ID,
+			Trace:    stack.Trace,
+			Hops:     stack.Hops,
+			Priority: stack.Priority,
+			Reliable: stack.Reliable,
+			Durable:  stack.Durable,
 		},
 		Data: message,
 	}
+	if r.router.maxStackDepth > 0 && len(newStack) > r.router.maxStackDepth {
+		r.router.drop(packet, dest, DropReasonStackDepth)
+		return
+	}
+	if (stack.Type == RoutePacketTypeUnicast || stack.Type == RoutePacketTypeReply) &&
+		r.router.looped(newStack, dest) {
+		r.router.drop(packet, dest, DropReasonLoop)
+		return
+	}
+	r.router.enqueue(packet)
 }
 
 // 发送广播包
 func (r *Route[T]) SendBroadcast(message T) {
-	r.router.messages <- RoutePacket[T]{
+	r.SendBroadcastContext(context.Background(), message)
+}
+
+// SendBroadcastContext 发送广播包，ctx 中的链路信息会作为该包的父节点
+func (r *Route[T]) SendBroadcastContext(ctx context.Context, message T) {
+	trace, _ := TraceFromContext(ctx)
+	r.router.enqueue(RoutePacket[T]{
 		Header: RoutePacketHeader{
-			Type:  RoutePacketTypeBroadcast,
-			Src:   r.name,
-			Dest:  "",
-			Stack: []string{r.name},
-			Ttl:   r.router.defaultTtl,
+			Type:     RoutePacketTypeBroadcast,
+			Src:      r.name,
+			Dest:     "",
+			Stack:    []string{r.name},
+			Ttl:      r.router.defaultTtl,
+			Trace:    trace,
+			Priority: priorityFromContext(ctx),
+
+			IdempotencyKey: idempotencyKeyFromContext(ctx),
 		},
 		Data: message,
-	}
+	})
 }
 
 // 发送组播包
 func (r *Route[T]) SendGroup(group string, message T) {
-	r.router.messages <- RoutePacket[T]{
+	r.router.enqueue(RoutePacket[T]{
 		Header: RoutePacketHeader{
 			Type:  RoutePacketTypeMulticast,
 			Src:   r.name,
@@ -175,7 +334,7 @@ func (r *Route[T]) SendGroup(group string, message T) {
 			Ttl:   r.router.defaultTtl,
 		},
 		Data: message,
-	}
+	})
 }
 
 // JoinGroup 加入组
@@ -187,7 +346,9 @@ func (r *Route[T]) JoinGroup(group string) error {
 	}
 	groupSet, _ := r.router.groups.LoadOrStore(group, mapset.NewSet[string]())
 	groupSet.Add(r.name)
-	r.groups.Add(group)
+	if r.groups.Add(group) {
+		r.router.emitTopology(TopologyEvent{Type: TopologyGroupJoined, Route: r.name, Group: group})
+	}
 	return nil
 }
 
@@ -200,7 +361,10 @@ func (r *Route[T]) LeaveGroup(group string) {
 		})
 	}
 	// 从路由的组列表中移除
-	r.groups.Remove(group)
+	if r.groups.Contains(group) {
+		r.groups.Remove(group)
+		r.router.emitTopology(TopologyEvent{Type: TopologyGroupLeft, Route: r.name, Group: group})
+	}
 }
 
 // AddFilter 添加过滤器
@@ -229,68 +393,132 @@ func (r *Route[T]) RemoveFilter(pattern string, handler *Filter[T]) error {
 }
 
 func (r *Route[T]) HandlerFunc(handler Handler[T]) {
-	r.handler = handler
+	if handler == nil {
+		r.handler = nil
+		return
+	}
+	r.handler = func(header RoutePacketHeader, data T) error {
+		handler(header, data)
+		return nil
+	}
 }
 
 func (r *Router[T]) Run() {
 	r.RunContext(context.Background())
 }
 func (r *Router[T]) RunContext(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go r.scheduler.run(ctx)
+	if r.journal != nil {
+		r.replayOnce.Do(func() {
+			go r.replay()
+		})
+	}
 	for {
-		select {
-		case <-ctx.Done():
+		packet, ok := r.lanes.next(ctx, r.done)
+		if !ok {
 			return
-		case packet := <-r.messages:
-			// TTL检查
-			if packet.Header.Ttl <= 0 {
-				continue
-			}
-			// 过滤器处理
-			filter, hasFilter := r.filter.Load(packet.Header.Dest)
-			if hasFilter && filter != nil {
-				finish := false
-				filters := make([]*Filter[T], 0)
-				filter.Range(func(key string, value *utils.Array[*Filter[T]]) bool {
-					filters = append(filters, value.Slice()...)
-					return true
-				})
-				for _, item := range filters {
-					f := *item
-					if f(packet.Header, packet.Data) {
-						finish = true
-						break
-					}
-				}
-				if finish {
-					// 包已经被拦截，跳过
-					continue
-				}
+		}
+		r.process(packet)
+	}
+}
+
+// 处理从队列中取出的包
+func (r *Router[T]) process(packet RoutePacket[T]) {
+	r.metrics.QueueDepth(r.lanes.depth())
+	r.tracer.Record(newSpan(SpanNameRoute, packet.Header, packet.Header.Hops[len(packet.Header.Hops)-1], r.clock.Now()))
+	if seq := packet.Header.journal; seq != 0 {
+		// 所有投递结束（包括被丢弃）后确认日志，确认失败的包重启后会再次投递
+		acked := packet
+		packet.delivery = newDelivery(func() {
+			if err := r.journal.Ack(seq); err != nil {
+				r.dropError(acked, acked.Header.Dest, DropReasonJournalAck, err)
 			}
-			// 根据包类型分发
-			switch packet.Header.Type {
-			case RoutePacketTypeUnicast:
-				r.handleUnicast(packet)
-			case RoutePacketTypeBroadcast:
-				r.handleBroadcast(packet)
-			case RoutePacketTypeMulticast:
-				r.handleMulticast(packet)
+		})
+		defer packet.delivery.done()
+	}
+	// TTL检查
+	if packet.Header.Ttl <= 0 {
+		r.metrics.PacketExpired(packet.Header.Type, packet.Header.Dest)
+		return
+	}
+	// 重复包检查
+	if r.duplicated(packet) {
+		// 重复包已经投递过，不进入死信
+		r.metrics.PacketDropped(packet.Header.Type, packet.Header.Dest, DropReasonDuplicate)
+		return
+	}
+	// 过滤器处理
+	filter, hasFilter := r.filter.Load(packet.Header.Dest)
+	if hasFilter && filter != nil {
+		finish := false
+		filters := make([]*Filter[T], 0)
+		filter.Range(func(key string, value *utils.Array[*Filter[T]]) bool {
+			filters = append(filters, value.Slice()...)
+			return true
+		})
+		for _, item := range filters {
+			f := *item
+			if f(packet.Header, packet.Data) {
+				finish = true
+				break
 			}
 		}
+		if finish {
+			// 包已经被拦截，跳过
+			r.metrics.PacketFiltered(packet.Header.Type, packet.Header.Dest)
+			return
+		}
+	}
+	// 发送路由与组限流
+	wait, ok := r.admit(packet)
+	if !ok {
+		r.drop(packet, packet.Header.Dest, DropReasonRateLimited)
+		return
+	}
+	if wait > 0 {
+		packet.delivery.add()
+		r.scheduler.schedule(r.clock.Now().Add(wait), func() {
+			defer packet.delivery.done()
+			r.dispatch(packet)
+		})
+		return
+	}
+	r.dispatch(packet)
+}
+
+// 根据包类型分发
+func (r *Router[T]) dispatch(packet RoutePacket[T]) {
+	switch packet.Header.Type {
+	case RoutePacketTypeUnicast:
+		r.handleUnicast(packet)
+	case RoutePacketTypeBroadcast:
+		r.handleBroadcast(packet)
+	case RoutePacketTypeMulticast:
+		r.handleMulticast(packet)
+	case RoutePacketTypeAnycast:
+		r.handleAnycast(packet)
+	case RoutePacketTypeReply:
+		r.handleReply(packet)
 	}
 }
 
 func (r *Router[T]) handleUnicast(packet RoutePacket[T]) {
 	if destRoute, ok := r.routes.Load(packet.Header.Dest); ok && destRoute.handler != nil {
-		go destRoute.handler(packet.Header, packet.Data)
+		r.deliver(destRoute, packet)
+		return
 	}
+	r.drop(packet, packet.Header.Dest, DropReasonNoRoute)
 }
 
 // 处理广播消息
 func (r *Router[T]) handleBroadcast(packet RoutePacket[T]) {
 	r.routes.Range(func(name string, route *Route[T]) bool {
 		// 不向发送者自身广播
-		if name != packet.Header.Src && route.handler != nil {
-			go route.handler(packet.Header, packet.Data)
+		if name != packet.Header.Src && route.handler != nil && !r.looped(packet.Header.Stack, name) &&
+			r.accepts(route, packet) {
+			r.deliver(route, packet)
 		}
 		return true
 	})
@@ -301,17 +529,114 @@ func (r *Router[T]) handleMulticast(packet RoutePacket[T]) {
 	if groupSet, ok := r.groups.Load(packet.Header.Dest); ok {
 		for _, memberName := range groupSet.ToSlice() {
 			// 不向发送者自身组播
-			if memberName != packet.Header.Src {
-				if memberRoute, ok := r.routes.Load(memberName); ok && memberRoute.handler != nil {
-					go memberRoute.handler(packet.Header, packet.Data)
+			if memberName != packet.Header.Src && !r.looped(packet.Header.Stack, memberName) {
+				if memberRoute, ok := r.routes.Load(memberName); ok && memberRoute.handler != nil &&
+					r.accepts(memberRoute, packet) {
+					r.deliver(memberRoute, packet)
 				}
 			}
 		}
+		return
+	}
+	r.drop(packet, packet.Header.Dest, DropReasonNoGroup)
+}
+
+// 开启环路检测时判断 name 是否已在转发栈中
+func (r *Router[T]) looped(stack []string, name string) bool {
+	return r.loopDetection && slices.Contains(stack, name)
+}
+
+// 将包放入队列，路由器停止后直接丢弃
+func (r *Router[T]) enqueue(packet RoutePacket[T]) {
+	packet.Header.Trace = packet.Header.Trace.child()
+	packet.Header.Hops = append(slices.Clone(packet.Header.Hops), r.clock.Now())
+	if packet.Header.Priority == PriorityUnset && r.classifier != nil {
+		packet.Header.Priority = r.classifier(packet.Header, packet.Data)
+	}
+	if packet.Header.Priority == PriorityUnset {
+		packet.Header.Priority = PriorityNormal
+	}
+	if err := r.persist(&packet); err != nil {
+		// 未能写入日志的持久包不能保证送达，交给死信处理
+		r.dropError(packet, packet.Header.Dest, DropReasonJournal, err)
+		return
+	}
+	if !r.push(packet) {
+		r.drop(packet, packet.Header.Dest, DropReasonStopped)
+		return
+	}
+	r.metrics.QueueDepth(r.lanes.depth())
+}
+
+// 将包放入对应优先级的队列，同步投递模式下不会阻塞，路由器停止后返回 false
+func (r *Router[T]) push(packet RoutePacket[T]) bool {
+	if r.synchronous {
+		select {
+		case <-r.done:
+			return false
+		default:
+		}
+		r.lanes.push(packet)
+		r.metrics.PacketSent(packet.Header.Type, packet.Header.Src)
+		return true
+	}
+	select {
+	case r.lanes.queue(packet.Header.Priority) <- packet:
+		r.metrics.PacketSent(packet.Header.Type, packet.Header.Src)
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+// 投递包到路由处理器
+func (r *Router[T]) deliver(route *Route[T], packet RoutePacket[T]) {
+	// 目标路由限流
+	wait, ok := r.throttle(RateLimitDest, route.name)
+	if !ok {
+		r.drop(packet, route.name, DropReasonRateLimited)
+		return
+	}
+	packet.delivery.add()
+	if wait > 0 {
+		r.scheduler.schedule(r.clock.Now().Add(wait), func() {
+			r.deliverAttempt(route, packet, 1)
+		})
+		return
+	}
+	r.deliverAttempt(route, packet, 1)
+}
+
+func (r *Router[T]) deliverAttempt(route *Route[T], packet RoutePacket[T], attempt int) {
+	route.inflight.Add(1)
+	run := func() {
+		defer route.inflight.Add(-1)
+		// 处理器看到的链路节点为本次投递，其后续发送的包都挂在该节点下
+		header := packet.Header
+		header.Trace = packet.Header.Trace.child()
+		start := r.clock.Now()
+		err := r.invoke(route, header, packet.Data)
+		end := r.clock.Now()
+		span := newSpan(SpanNameDeliver, header, start, end)
+		span.Route = route.name
+		r.tracer.Record(span)
+		r.metrics.HandlerLatency(route.name, end.Sub(start))
+		if err != nil && packet.Header.Reliable {
+			r.retry(route.name, packet, attempt, err)
+			return
+		}
+		r.metrics.PacketDelivered(packet.Header.Type, route.name)
+		packet.delivery.done()
+	}
+	if r.synchronous {
+		run()
+		return
 	}
+	go run()
 }
 
 func (r *Router[T]) Stop() {
 	r.once.Do(func() {
-		close(r.messages)
+		close(r.done)
 	})
 }
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"cmp"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/greek-milk-bot/core/utils"
+)
+
+// AnycastStrategy 任播策略，从组成员中挑选一个接收者
+//
+// members 已按名称排序且不包含发送者，保证非空；返回 nil 时包被丢弃
+type AnycastStrategy[T any] interface {
+	Select(header RoutePacketHeader, data T, members []*Route[T]) *Route[T]
+}
+
+// AnycastStrategyFunc 函数形式的任播策略
+type AnycastStrategyFunc[T any] func(header RoutePacketHeader, data T, members []*Route[T]) *Route[T]
+
+func (f AnycastStrategyFunc[T]) Select(header RoutePacketHeader, data T, members []*Route[T]) *Route[T] {
+	return f(header, data, members)
+}
+
+// SendAnycast 发送任播包，仅投递给组内的一个成员
+func (r *Route[T]) SendAnycast(group string, message T) {
+	r.router.enqueue(RoutePacket[T]{
+		Header: RoutePacketHeader{
+			Type:  RoutePacketTypeAnycast,
+			Src:   r.name,
+			Dest:  group,
+			Stack: []string{r.name},
+			Ttl:   r.router.defaultTtl,
+		},
+		Data: message,
+	})
+}
+
+// SetAnycastStrategy 设置组的任播策略，strategy 为 nil 时恢复默认策略
+func (r *Router[T]) SetAnycastStrategy(group string, strategy AnycastStrategy[T]) {
+	if strategy == nil {
+		r.strategies.LoadAndDelete(group)
+		return
+	}
+	r.strategies.Store(group, strategy)
+}
+
+// 处理任播消息
+func (r *Router[T]) handleAnycast(packet RoutePacket[T]) {
+	groupSet, ok := r.groups.Load(packet.Header.Dest)
+	if !ok {
+		r.drop(packet, packet.Header.Dest, DropReasonNoGroup)
+		return
+	}
+	names := groupSet.ToSlice()
+	sort.Strings(names)
+	members := make([]*Route[T], 0, len(names))
+	for _, memberName := range names {
+		// 不向发送者自身任播
+		if memberName == packet.Header.Src || r.looped(packet.Header.Stack, memberName) {
+			continue
+		}
+		if memberRoute, ok := r.routes.Load(memberName); ok && memberRoute.handler != nil &&
+			r.accepts(memberRoute, packet) {
+			members = append(members, memberRoute)
+		}
+	}
+	if len(members) == 0 {
+		r.drop(packet, packet.Header.Dest, DropReasonNoGroup)
+		return
+	}
+	strategy, ok := r.strategies.Load(packet.Header.Dest)
+	if !ok {
+		strategy = r.defaultStrategy
+	}
+	target := strategy.Select(packet.Header, packet.Data, members)
+	if target == nil {
+		r.drop(packet, packet.Header.Dest, DropReasonNoGroup)
+		return
+	}
+	r.deliver(target, packet)
+}
+
+// NewRoundRobinStrategy 轮询策略，每个组独立计数
+func NewRoundRobinStrategy[T any]() AnycastStrategy[T] {
+	counters := utils.NewMap[string, *atomic.Uint64]()
+	return AnycastStrategyFunc[T](func(header RoutePacketHeader, _ T, members []*Route[T]) *Route[T] {
+		counter, _ := counters.LoadOrStore(header.Dest, new(atomic.Uint64))
+		next := counter.Add(1) - 1
+		return members[next%uint64(len(members))]
+	})
+}
+
+// NewRandomStrategy 随机策略
+func NewRandomStrategy[T any]() AnycastStrategy[T] {
+	return AnycastStrategyFunc[T](func(_ RoutePacketHeader, _ T, members []*Route[T]) *Route[T] {
+		return members[rand.IntN(len(members))]
+	})
+}
+
+// NewLeastInFlightStrategy 最少处理中策略，多个成员并列时随机挑选
+func NewLeastInFlightStrategy[T any]() AnycastStrategy[T] {
+	return AnycastStrategyFunc[T](func(_ RoutePacketHeader, _ T, members []*Route[T]) *Route[T] {
+		var candidates []*Route[T]
+		least := int64(-1)
+		for _, member := range members {
+			current := member.InFlight()
+			switch {
+			case least < 0 || current < least:
+				least = current
+				candidates = append(candidates[:0], member)
+			case current == least:
+				candidates = append(candidates, member)
+			}
+		}
+		return candidates[rand.IntN(len(candidates))]
+	})
+}
+
+// 一致性哈希中每个成员的虚拟节点数量
+const consistentHashReplicas = 64
+
+// NewConsistentHashStrategy 一致性哈希策略，相同 key 的包总是投递到同一个成员，
+// 成员变动时只影响少部分 key 的归属
+//
+// 每个组的哈希环只在候选成员变化时重建
+func NewConsistentHashStrategy[T any](key func(header RoutePacketHeader, data T) string) AnycastStrategy[T] {
+	return &consistentHashStrategy[T]{
+		key:   key,
+		rings: utils.NewMap[string, *hashRing[T]](),
+	}
+}
+
+type consistentHashStrategy[T any] struct {
+	key   func(header RoutePacketHeader, data T) string
+	rings *utils.Map[string, *hashRing[T]] // 组 - 哈希环
+}
+
+func (s *consistentHashStrategy[T]) Select(header RoutePacketHeader, data T, members []*Route[T]) *Route[T] {
+	ring, ok := s.rings.Load(header.Dest)
+	if !ok || !slices.Equal(ring.members, members) {
+		ring = newHashRing(members)
+		s.rings.Store(header.Dest, ring)
+	}
+	return ring.get(s.key(header, data))
+}
+
+// 一致性哈希环
+type hashRing[T any] struct {
+	members []*Route[T] // 构建时的候选成员
+	nodes   []hashNode[T]
+}
+
+type hashNode[T any] struct {
+	hash  uint32
+	route *Route[T]
+}
+
+func newHashRing[T any](members []*Route[T]) *hashRing[T] {
+	nodes := make([]hashNode[T], 0, len(members)*consistentHashReplicas)
+	for _, member := range members {
+		for i := 0; i < consistentHashReplicas; i++ {
+			nodes = append(nodes, hashNode[T]{
+				hash:  hashString(member.name + "#" + strconv.Itoa(i)),
+				route: member,
+			})
+		}
+	}
+	slices.SortFunc(nodes, func(a, b hashNode[T]) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+	return &hashRing[T]{members: slices.Clone(members), nodes: nodes}
+}
+
+// 返回 key 顺时针方向的第一个成员
+func (ring *hashRing[T]) get(key string) *Route[T] {
+	h := hashString(key)
+	index := sort.Search(len(ring.nodes), func(i int) bool {
+		return ring.nodes[i].hash >= h
+	})
+	if index == len(ring.nodes) {
+		index = 0
+	}
+	return ring.nodes[index].route
+}
+
+func hashString(s string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum32()
+}
@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试任播只投递给一个成员，默认轮询
+func TestAnycastRoundRobin(t *testing.T) {
+	router := NewRouter[string](64)
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	var counts [3]atomic.Int32
+	var wg sync.WaitGroup
+	for i, name := range []string{"worker0", "worker1", "worker2"} {
+		worker, _ := router.AddRoute(name)
+		assert.NoError(t, worker.JoinGroup("workers"))
+		worker.HandlerFunc(func(header RoutePacketHeader, data string) {
+			defer wg.Done()
+			assert.Equal(t, RoutePacketTypeAnycast, header.Type)
+			assert.Equal(t, "workers", header.Dest)
+			counts[i].Add(1)
+		})
+	}
+	// 发送者加入组后也不应收到自己的包
+	assert.NoError(t, sender.JoinGroup("workers"))
+	sender.HandlerFunc(func(header RoutePacketHeader, data string) {
+		t.Error("发送者不应收到任播包")
+	})
+
+	wg.Add(6)
+	for i := 0; i < 6; i++ {
+		sender.SendAnycast("workers", "job")
+	}
+	wg.Wait()
+
+	for i := range counts {
+		assert.Equal(t, int32(2), counts[i].Load())
+	}
+}
+
+// 测试一致性哈希策略相同 key 总是落在同一成员
+func TestAnycastConsistentHash(t *testing.T) {
+	router := NewRouter[string](64)
+	go router.Run()
+	defer router.Stop()
+
+	router.SetAnycastStrategy("workers", NewConsistentHashStrategy[string](func(header RoutePacketHeader, data string) string {
+		return data
+	}))
+
+	sender, _ := router.AddRoute("sender")
+	var mu sync.Mutex
+	received := make(map[string]map[string]int)
+	var wg sync.WaitGroup
+	for _, name := range []string{"worker0", "worker1", "worker2", "worker3"} {
+		worker, _ := router.AddRoute(name)
+		assert.NoError(t, worker.JoinGroup("workers"))
+		worker.HandlerFunc(func(header RoutePacketHeader, data string) {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			if received[data] == nil {
+				received[data] = make(map[string]int)
+			}
+			received[data][name]++
+		})
+	}
+
+	keys := []string{"guild-a", "guild-b", "guild-c", "guild-d", "guild-e"}
+	wg.Add(len(keys) * 4)
+	for i := 0; i < 4; i++ {
+		for _, key := range keys {
+			sender.SendAnycast("workers", key)
+		}
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		assert.Len(t, received[key], 1, "key %s 应只落在一个成员", key)
+	}
+}
+
+// 测试一致性哈希环只在成员变化时重建
+func TestConsistentHashRingCache(t *testing.T) {
+	router := NewRouter[string](64)
+	var members []*Route[string]
+	for _, name := range []string{"worker0", "worker1", "worker2"} {
+		member, _ := router.AddRoute(name)
+		members = append(members, member)
+	}
+	strategy := NewConsistentHashStrategy[string](func(header RoutePacketHeader, data string) string {
+		return data
+	}).(*consistentHashStrategy[string])
+	header := RoutePacketHeader{Type: RoutePacketTypeAnycast, Dest: "workers"}
+
+	target := strategy.Select(header, "guild-a", members)
+	ring, _ := strategy.rings.Load("workers")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, target, strategy.Select(header, "guild-a", slices.Clone(members)))
+	}
+	cached, _ := strategy.rings.Load("workers")
+	assert.Same(t, ring, cached)
+
+	// 成员变化后重建
+	strategy.Select(header, "guild-a", members[:2])
+	cached, _ = strategy.rings.Load("workers")
+	assert.NotSame(t, ring, cached)
+	assert.Equal(t, members[:2], cached.members)
+}
+
+// 测试策略没有选出成员时丢弃包
+func TestAnycastNoSelection(t *testing.T) {
+	drops := &dropRecorder{}
+	router := NewRouter[string](64, WithSynchronousDelivery[string](), WithMetrics[string](drops))
+	router.SetAnycastStrategy("workers", AnycastStrategyFunc[string](func(RoutePacketHeader, string, []*Route[string]) *Route[string] {
+		return nil
+	}))
+	sender, _ := router.AddRoute("sender")
+	worker, _ := router.AddRoute("worker")
+	assert.NoError(t, worker.JoinGroup("workers"))
+	received := 0
+	worker.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received++
+	})
+
+	sender.SendAnycast("workers", "job")
+	router.Flush()
+	assert.Equal(t, 0, received)
+	assert.Equal(t, []string{"workers:" + DropReasonNoGroup}, drops.Reasons())
+}
+
+// 测试最少处理中策略避开繁忙成员
+func TestAnycastLeastInFlight(t *testing.T) {
+	router := NewRouter[string](64)
+	busy, _ := router.AddRoute("busy")
+	idle, _ := router.AddRoute("idle")
+	busy.inflight.Add(3)
+	idle.inflight.Add(1)
+
+	strategy := NewLeastInFlightStrategy[string]()
+	header := RoutePacketHeader{Type: RoutePacketTypeAnycast, Dest: "workers"}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, idle, strategy.Select(header, "job", []*Route[string]{busy, idle}))
+	}
+}
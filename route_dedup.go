@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// 未设置时间窗口与容量时最多记录的去重键数量
+const defaultDedupSize = 4096
+
+// WithDeduplication 开启重复包过滤
+//
+// 包的去重键优先取包头中的 IdempotencyKey，为空时使用 key 计算（key 可为 nil），
+// 去重键为空的包不参与去重。window 内出现过的去重键会被丢弃，最多记录 size 个去重键，
+// 超出时淘汰最早的记录；window 与 size 都不大于 0 时最多记录 4096 个。转发包沿用原包数据，不参与去重。
+//
+// 去重键在所有发送方之间共享，不同路由发送相同去重键的包同样视为重复，
+// 需要按发送方区分时由 key 在去重键中加入 header.Src。
+func WithDeduplication[T any](window time.Duration, size int, key func(header RoutePacketHeader, data T) string) RouterOption[T] {
+	if window <= 0 && size <= 0 {
+		size = defaultDedupSize
+	}
+	return func(r *Router[T]) {
+		r.dedupKey = key
+		r.seen = newSeenSet(window, size)
+	}
+}
+
+type idempotencyContextKey struct{}
+
+// ContextWithIdempotencyKey 将去重键放入 ctx，通过 ctx 发送的包使用该去重键
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyContextKey{}, key)
+}
+
+func idempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyContextKey{}).(string)
+	return key
+}
+
+// 判断包是否重复，非重复包会被记录
+func (r *Router[T]) duplicated(packet RoutePacket[T]) bool {
+	if r.seen == nil || len(packet.Header.Stack) > 1 {
+		return false
+	}
+	key := packet.Header.IdempotencyKey
+	if key == "" && r.dedupKey != nil {
+		key = r.dedupKey(packet.Header, packet.Data)
+	}
+	if key == "" {
+		return false
+	}
+	return !r.seen.add(key, r.clock.Now())
+}
+
+// 有时间与容量上限的已见集合
+type seenSet struct {
+	window time.Duration
+	size   int
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List // 按首次出现时间排列
+}
+
+type seenEntry struct {
+	key  string
+	seen time.Time
+}
+
+func newSeenSet(window time.Duration, size int) *seenSet {
+	return &seenSet{
+		window: window,
+		size:   size,
+		items:  make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// 记录 key，已存在且未过期时返回 false
+func (s *seenSet) add(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 淘汰过期记录
+	for s.window > 0 {
+		front := s.order.Front()
+		if front == nil || now.Sub(front.Value.(*seenEntry).seen) < s.window {
+			break
+		}
+		s.order.Remove(front)
+		delete(s.items, front.Value.(*seenEntry).key)
+	}
+	if _, ok := s.items[key]; ok {
+		return false
+	}
+	s.items[key] = s.order.PushBack(&seenEntry{key: key, seen: now})
+	for s.size > 0 && s.order.Len() > s.size {
+		front := s.order.Front()
+		s.order.Remove(front)
+		delete(s.items, front.Value.(*seenEntry).key)
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试重复包在过滤器与处理器之前被丢弃
+func TestDeduplication(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	drops := &dropRecorder{}
+	router := NewRouter[string](64,
+		WithClock[string](clock),
+		WithMetrics[string](drops),
+		WithDeduplication[string](time.Minute, 100, func(header RoutePacketHeader, data string) string {
+			if id, ok := strings.CutPrefix(data, "msg:"); ok {
+				return id
+			}
+			return ""
+		}),
+	)
+	go router.Run()
+	defer router.Stop()
+
+	adapter, _ := router.AddRoute("adapter")
+	receiver, _ := router.AddRoute("receiver")
+
+	var mu sync.Mutex
+	var received []string
+	var filtered int
+	countFilter := Filter[string](func(header RoutePacketHeader, data string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		filtered++
+		return false
+	})
+	assert.NoError(t, receiver.AddFilter("receiver", &countFilter))
+	receiver.HandlerFunc(func(header RoutePacketHeader, data string) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, data)
+	})
+
+	// 通过数据提取去重键
+	adapter.Send("receiver", "msg:1")
+	adapter.Send("receiver", "msg:1")
+	// 通过包头去重键
+	ctx := ContextWithIdempotencyKey(context.Background(), "event-7")
+	adapter.SendContext(ctx, "receiver", "event")
+	adapter.SendContext(ctx, "receiver", "event")
+	// 没有去重键的包不参与去重
+	adapter.Send("receiver", "plain")
+	adapter.Send("receiver", "plain")
+	time.Sleep(100 * time.Millisecond)
+
+	// 超过时间窗口后可以再次通过
+	clock.Advance(time.Minute)
+	adapter.Send("receiver", "msg:1")
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"msg:1", "event", "plain", "plain", "msg:1"}, received)
+	assert.Equal(t, 5, filtered)
+	assert.Equal(t, []string{"receiver:duplicate", "receiver:duplicate"}, drops.Reasons())
+}
+
+// 测试已见集合的容量上限
+func TestSeenSetSize(t *testing.T) {
+	seen := newSeenSet(0, 2)
+	now := time.Unix(0, 0)
+	assert.True(t, seen.add("a", now))
+	assert.True(t, seen.add("b", now))
+	assert.False(t, seen.add("a", now))
+	assert.True(t, seen.add("c", now))
+	// a 因容量淘汰后可再次加入
+	assert.True(t, seen.add("a", now))
+	assert.False(t, seen.add("c", now))
+}
+
+// 测试未设置时间窗口与容量时使用默认容量
+func TestDeduplicationDefaultSize(t *testing.T) {
+	router := NewRouter[int](64, WithSynchronousDelivery[int](), WithDeduplication[int](0, 0, func(header RoutePacketHeader, data int) string {
+		return strconv.Itoa(data)
+	}))
+	sender, _ := router.AddRoute("sender")
+	receiver, _ := router.AddRoute("receiver")
+	receiver.HandlerFunc(func(header RoutePacketHeader, data int) {})
+
+	for i := range defaultDedupSize + 1000 {
+		sender.Send("receiver", i)
+	}
+	router.Flush()
+	assert.Len(t, router.seen.items, defaultDedupSize)
+	assert.Equal(t, defaultDedupSize, router.seen.order.Len())
+}
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// JournalCodec 持久化包数据的编解码
+type JournalCodec[T any] interface {
+	Encode(data T) ([]byte, error)
+	Decode(payload []byte) (T, error)
+}
+
+type jsonCodec[T any] struct{}
+
+// JSONCodec 基于 encoding/json 的编解码
+func JSONCodec[T any]() JournalCodec[T] {
+	return jsonCodec[T]{}
+}
+
+func (jsonCodec[T]) Encode(data T) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (jsonCodec[T]) Decode(payload []byte) (T, error) {
+	var data T
+	err := json.Unmarshal(payload, &data)
+	return data, err
+}
+
+// WithJournal 为持久包开启预写日志，codec 为 nil 时使用 JSON 编解码
+//
+// 日志中未确认的包会在路由器首次运行时重新投递，日志的生命周期由调用方管理
+func WithJournal[T any](journal *Journal, codec JournalCodec[T]) RouterOption[T] {
+	return func(r *Router[T]) {
+		if codec == nil {
+			codec = JSONCodec[T]()
+		}
+		r.journal = journal
+		r.journalCodec = codec
+		r.replayEntries = journal.Pending()
+	}
+}
+
+type journalRecord struct {
+	Header RoutePacketHeader `json:"header"`
+	Data   json.RawMessage   `json:"data"`
+}
+
+// SendDurable 发送持久单播包，包在确认前会记录到日志中，持久包总是可靠包
+func (r *Route[T]) SendDurable(dest string, message T) {
+	r.router.enqueue(RoutePacket[T]{
+		Header: RoutePacketHeader{
+			Type:     RoutePacketTypeUnicast,
+			Src:      r.name,
+			Dest:     dest,
+			Stack:    []string{r.name},
+			Ttl:      r.router.defaultTtl,
+			Reliable: true,
+			Durable:  true,
+		},
+		Data: message,
+	})
+}
+
+// SendGroupDurable 发送持久组播包，所有成员确认后才从日志中移除
+func (r *Route[T]) SendGroupDurable(group string, message T) {
+	r.router.enqueue(RoutePacket[T]{
+		Header: RoutePacketHeader{
+			Type:     RoutePacketTypeMulticast,
+			Src:      r.name,
+			Dest:     group,
+			Stack:    []string{r.name},
+			Ttl:      r.router.defaultTtl,
+			Reliable: true,
+			Durable:  true,
+		},
+		Data: message,
+	})
+}
+
+// 将持久包写入日志，写入失败时返回错误
+func (r *Router[T]) persist(packet *RoutePacket[T]) error {
+	if r.journal == nil || !packet.Header.Durable || packet.Header.journal != 0 {
+		return nil
+	}
+	packet.Header.Reliable = true
+	data, err := r.journalCodec.Encode(packet.Data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(journalRecord{Header: packet.Header, Data: data})
+	if err != nil {
+		return err
+	}
+	seq, err := r.journal.Append(payload)
+	if err != nil {
+		return err
+	}
+	packet.Header.journal = seq
+	return nil
+}
+
+// 重新投递上次运行时未确认的包
+func (r *Router[T]) replay() {
+	entries := r.replayEntries
+	r.replayEntries = nil
+	for _, entry := range entries {
+		var record journalRecord
+		if err := json.Unmarshal(entry.Payload, &record); err != nil {
+			continue
+		}
+		data, err := r.journalCodec.Decode(record.Data)
+		if err != nil {
+			continue
+		}
+		record.Header.journal = entry.Seq
+		packet := RoutePacket[T]{Header: record.Header, Data: data}
+		if !r.push(packet) {
+			return
+		}
+	}
+}
+
+// 持久包的投递计数，所有投递结束后确认日志
+type delivery struct {
+	pending atomic.Int64
+	ack     func()
+}
+
+func newDelivery(ack func()) *delivery {
+	d := &delivery{ack: ack}
+	d.pending.Store(1)
+	return d
+}
+
+func (d *delivery) add() {
+	if d != nil {
+		d.pending.Add(1)
+	}
+}
+
+func (d *delivery) done() {
+	if d != nil && d.pending.Add(-1) == 0 {
+		d.ack()
+	}
+}
@@ -0,0 +1,33 @@
+package bot
+
+import "errors"
+
+// Predicate 接收方判断条件，返回 false 时该路由不接收此包
+//
+// 与 Filter 不同，Predicate 只影响注册它的路由，不会拦截发往其他路由的包
+type Predicate[T any] func(header RoutePacketHeader, data T) bool
+
+// AddPredicate 添加接收广播、组播与任播包前的判断条件，任播只在满足条件的成员中挑选，所有条件都满足时才投递
+func (r *Route[T]) AddPredicate(predicate *Predicate[T]) error {
+	if !r.predicates.AddIfNotExists(predicate) {
+		return errors.New("predicate already exists")
+	}
+	return nil
+}
+
+// RemovePredicate 移除判断条件
+func (r *Route[T]) RemovePredicate(predicate *Predicate[T]) {
+	r.predicates.DeleteByValue(predicate)
+}
+
+// 判断路由是否接收广播、组播或任播包
+func (r *Router[T]) accepts(route *Route[T], packet RoutePacket[T]) bool {
+	for _, item := range route.predicates.Slice() {
+		predicate := *item
+		if !predicate(packet.Header, packet.Data) {
+			r.metrics.PacketFiltered(packet.Header.Type, route.name)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试接收方判断条件只影响注册它的路由
+func TestPredicate(t *testing.T) {
+	router := NewRouter[string](64, WithSynchronousDelivery[string]())
+	sender, _ := router.AddRoute("sender")
+	picky, _ := router.AddRoute("picky")
+	other, _ := router.AddRoute("other")
+	assert.NoError(t, picky.JoinGroup("group"))
+	assert.NoError(t, other.JoinGroup("group"))
+
+	received := map[string][]string{}
+	record := func(name string) Handler[string] {
+		return func(header RoutePacketHeader, data string) {
+			received[name] = append(received[name], data)
+		}
+	}
+	picky.HandlerFunc(record("picky"))
+	other.HandlerFunc(record("other"))
+
+	guild := Predicate[string](func(header RoutePacketHeader, data string) bool {
+		return strings.HasPrefix(data, "guild-x:")
+	})
+	assert.NoError(t, picky.AddPredicate(&guild))
+	assert.Error(t, picky.AddPredicate(&guild))
+
+	sender.SendBroadcast("guild-x:hello")
+	sender.SendBroadcast("guild-y:hello")
+	sender.SendGroup("group", "guild-y:group")
+	// 单播不受判断条件影响
+	sender.Send("picky", "direct")
+	router.Flush()
+
+	assert.Equal(t, []string{"guild-x:hello", "direct"}, received["picky"])
+	assert.Equal(t, []string{"guild-x:hello", "guild-y:hello", "guild-y:group"}, received["other"])
+
+	picky.RemovePredicate(&guild)
+	sender.SendBroadcast("guild-y:again")
+	router.Flush()
+	assert.Equal(t, []string{"guild-x:hello", "direct", "guild-y:again"}, received["picky"])
+}
+
+// 测试任播只在满足判断条件的成员中挑选
+func TestPredicateAnycast(t *testing.T) {
+	router := NewRouter[string](64, WithSynchronousDelivery[string]())
+	sender, _ := router.AddRoute("sender")
+	picky, _ := router.AddRoute("picky")
+	other, _ := router.AddRoute("other")
+	assert.NoError(t, picky.JoinGroup("group"))
+	assert.NoError(t, other.JoinGroup("group"))
+
+	received := map[string][]string{}
+	picky.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received["picky"] = append(received["picky"], data)
+	})
+	other.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received["other"] = append(received["other"], data)
+	})
+	never := Predicate[string](func(header RoutePacketHeader, data string) bool {
+		return false
+	})
+	assert.NoError(t, picky.AddPredicate(&never))
+
+	for _, data := range []string{"a", "b", "c"} {
+		sender.SendAnycast("group", data)
+	}
+	router.Flush()
+	assert.Empty(t, received["picky"])
+	assert.Equal(t, []string{"a", "b", "c"}, received["other"])
+}
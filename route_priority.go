@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority 包优先级，不同优先级的包进入不同的队列，数值越大优先级越高
+type Priority uint8
+
+const (
+	PriorityUnset  Priority = iota // 未指定，进入队列时由分类器决定，没有分类器时为 PriorityNormal
+	PriorityLow                    // 大量且可延后的包，例如广播事件
+	PriorityNormal                 // 默认优先级
+	PriorityHigh                   // 控制类包，例如调用应答与元数据
+	PriorityUrgent                 // 紧急包，例如停机通知
+
+	priorityCount = iota
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityUnset:
+		return "unset"
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityUrgent:
+		return "urgent"
+	}
+	return "unknown"
+}
+
+// 调度顺序，优先级高的队列先被轮询
+var laneOrder = [...]Priority{PriorityUrgent, PriorityHigh, PriorityNormal, PriorityLow}
+
+// DefaultLaneWeights 默认的队列权重，每轮调度中各队列最多连续取出的包数量
+var DefaultLaneWeights = map[Priority]int{
+	PriorityUrgent: 16,
+	PriorityHigh:   8,
+	PriorityNormal: 4,
+	PriorityLow:    1,
+}
+
+// WithLaneWeights 设置各优先级队列的权重，未设置或不大于 0 的队列使用默认权重
+func WithLaneWeights[T any](weights map[Priority]int) RouterOption[T] {
+	return func(r *Router[T]) {
+		for priority, weight := range weights {
+			if priority > PriorityUnset && priority < priorityCount && weight > 0 {
+				r.lanes.weights[priority] = weight
+			}
+		}
+	}
+}
+
+// WithPriorityClassifier 为未指定优先级的包计算优先级，分类器返回 PriorityUnset 时使用 PriorityNormal
+func WithPriorityClassifier[T any](classifier func(header RoutePacketHeader, data T) Priority) RouterOption[T] {
+	return func(r *Router[T]) {
+		r.classifier = classifier
+	}
+}
+
+type priorityContextKey struct{}
+
+// ContextWithPriority 将优先级放入 ctx，通过 ctx 发送的包使用该优先级
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+func priorityFromContext(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityContextKey{}).(Priority)
+	return priority
+}
+
+// 多优先级队列，按权重轮询，保证低优先级队列不会饿死
+type lanes[T any] struct {
+	queues  [priorityCount]chan RoutePacket[T] // PriorityUnset 没有对应的队列
+	weights [priorityCount]int
+
+	// 同步投递模式下使用无界队列，避免在没有消费者时阻塞发送方
+	unbounded bool
+	mu        sync.Mutex
+	pending   [priorityCount][]RoutePacket[T]
+
+	// 以下状态只在路由协程中访问
+	current int // 当前轮询到的 laneOrder 下标
+	budget  int // 当前队列剩余可取数量
+}
+
+func newLanes[T any](size int) *lanes[T] {
+	l := &lanes[T]{}
+	for _, priority := range laneOrder {
+		l.queues[priority] = make(chan RoutePacket[T], size)
+	}
+	for priority, weight := range DefaultLaneWeights {
+		l.weights[priority] = weight
+	}
+	l.budget = l.weights[laneOrder[0]]
+	return l
+}
+
+func (l *lanes[T]) queue(priority Priority) chan RoutePacket[T] {
+	if priority == PriorityUnset || priority >= priorityCount {
+		priority = PriorityNormal
+	}
+	return l.queues[priority]
+}
+
+// 放入无界队列
+func (l *lanes[T]) push(packet RoutePacket[T]) {
+	priority := packet.Header.Priority
+	if priority == PriorityUnset || priority >= priorityCount {
+		priority = PriorityNormal
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending[priority] = append(l.pending[priority], packet)
+}
+
+// 非阻塞取出一个优先级的包
+func (l *lanes[T]) take(priority Priority) (RoutePacket[T], bool) {
+	if l.unbounded {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if pending := l.pending[priority]; len(pending) > 0 {
+			packet := pending[0]
+			pending[0] = RoutePacket[T]{}
+			l.pending[priority] = pending[1:]
+			return packet, true
+		}
+	}
+	select {
+	case packet := <-l.queues[priority]:
+		return packet, true
+	default:
+		var zero RoutePacket[T]
+		return zero, false
+	}
+}
+
+// 队列中等待处理的包数量
+func (l *lanes[T]) depth() int {
+	total := 0
+	for _, queue := range l.queues {
+		total += len(queue)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, pending := range l.pending {
+		total += len(pending)
+	}
+	return total
+}
+
+// 按权重非阻塞取出一个包
+func (l *lanes[T]) poll() (RoutePacket[T], bool) {
+	for range len(laneOrder) + 1 {
+		if l.budget > 0 {
+			if packet, ok := l.take(laneOrder[l.current]); ok {
+				l.budget--
+				return packet, true
+			}
+		}
+		l.current = (l.current + 1) % len(laneOrder)
+		l.budget = l.weights[laneOrder[l.current]]
+	}
+	var zero RoutePacket[T]
+	return zero, false
+}
+
+// 取出下一个包，所有队列为空时阻塞等待
+func (l *lanes[T]) next(ctx context.Context, done <-chan struct{}) (RoutePacket[T], bool) {
+	if packet, ok := l.poll(); ok {
+		return packet, true
+	}
+	var zero RoutePacket[T]
+	select {
+	case <-ctx.Done():
+		return zero, false
+	case <-done:
+		return zero, false
+	case packet := <-l.queues[PriorityUrgent]:
+		return packet, true
+	case packet := <-l.queues[PriorityHigh]:
+		return packet, true
+	case packet := <-l.queues[PriorityNormal]:
+		return packet, true
+	case packet := <-l.queues[PriorityLow]:
+		return packet, true
+	}
+}
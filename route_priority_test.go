@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试按权重轮询各优先级队列
+func TestLanesWeightedPoll(t *testing.T) {
+	l := newLanes[string](16)
+	l.weights[PriorityHigh] = 2
+	l.weights[PriorityNormal] = 3
+	l.weights[PriorityLow] = 1
+	l.budget = l.weights[laneOrder[0]]
+
+	for _, data := range []string{"n1", "n2", "n3", "n4", "n5"} {
+		l.queue(PriorityNormal) <- RoutePacket[string]{Data: data}
+	}
+	for _, data := range []string{"l1", "l2"} {
+		l.queue(PriorityLow) <- RoutePacket[string]{Data: data}
+	}
+	for _, data := range []string{"h1", "h2", "h3"} {
+		l.queue(PriorityHigh) <- RoutePacket[string]{Data: data}
+	}
+	assert.Equal(t, 10, l.depth())
+
+	var order []string
+	for {
+		packet, ok := l.poll()
+		if !ok {
+			break
+		}
+		order = append(order, packet.Data)
+	}
+	assert.Equal(t, []string{
+		"h1", "h2", "n1", "n2", "n3", "l1",
+		"h3", "n4", "n5", "l2",
+	}, order)
+	assert.Equal(t, 0, l.depth())
+}
+
+// 测试优先级来源：ctx、分类器与应答
+func TestPriorityAssignment(t *testing.T) {
+	router := NewRouter[string](64, WithPriorityClassifier(func(header RoutePacketHeader, data string) Priority {
+		if data == "spam" {
+			return PriorityLow
+		}
+		return PriorityNormal
+	}))
+	route, _ := router.AddRoute("route")
+
+	route.Send("dest", "spam")
+	route.SendContext(ContextWithPriority(context.Background(), PriorityUrgent), "dest", "shutdown")
+	route.Send("dest", "chat")
+	assert.NoError(t, route.Reply(RoutePacketHeader{Src: "dest", ID: "1"}, "resp"))
+	// 显式指定的 PriorityNormal 不会被分类器覆盖
+	route.SendContext(ContextWithPriority(context.Background(), PriorityNormal), "dest", "spam")
+
+	assert.Equal(t, "spam", (<-router.lanes.queue(PriorityLow)).Data)
+	assert.Equal(t, "shutdown", (<-router.lanes.queue(PriorityUrgent)).Data)
+	chat := <-router.lanes.queue(PriorityNormal)
+	assert.Equal(t, "chat", chat.Data)
+	assert.Equal(t, PriorityNormal, chat.Header.Priority)
+	assert.Equal(t, "resp", (<-router.lanes.queue(PriorityHigh)).Data)
+	assert.Equal(t, "spam", (<-router.lanes.queue(PriorityNormal)).Data)
+}
+
+// 测试优先级的数值顺序与等级一致
+func TestPriorityOrder(t *testing.T) {
+	assert.Less(t, PriorityUnset, PriorityLow)
+	assert.Less(t, PriorityLow, PriorityNormal)
+	assert.Less(t, PriorityNormal, PriorityHigh)
+	assert.Less(t, PriorityHigh, PriorityUrgent)
+	assert.Equal(t, "unset", PriorityUnset.String())
+}
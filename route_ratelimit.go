@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimitScope 限流作用范围
+type RateLimitScope uint8
+
+const (
+	RateLimitDest  RateLimitScope = iota // 按目标路由限流，每次投递消耗一个令牌
+	RateLimitSrc                         // 按发送路由限流，每个包消耗一个令牌
+	RateLimitGroup                       // 按组限流，每个组播或任播包消耗一个令牌
+)
+
+func (s RateLimitScope) String() string {
+	switch s {
+	case RateLimitDest:
+		return "dest"
+	case RateLimitSrc:
+		return "src"
+	case RateLimitGroup:
+		return "group"
+	}
+	return "unknown"
+}
+
+// RateLimitMode 超出限制时的处理方式
+type RateLimitMode uint8
+
+const (
+	RateLimitDrop  RateLimitMode = iota // 直接丢弃
+	RateLimitDelay                      // 延迟到有令牌时再处理
+)
+
+// RateLimit 令牌桶限流配置
+type RateLimit struct {
+	Rate     float64       // 每秒补充的令牌数
+	Burst    int           // 桶容量，小于 1 时按 1 处理
+	Mode     RateLimitMode // 超出限制时的处理方式
+	MaxDelay time.Duration // 延迟模式下的最长等待时间，超过后丢弃，0 表示不限制
+}
+
+type rateLimitKey struct {
+	scope RateLimitScope
+	name  string
+}
+
+// 令牌桶，延迟模式下令牌可以预支为负数
+type tokenBucket struct {
+	mu     sync.Mutex
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// 取出一个令牌，返回需要等待的时间，不允许通过时返回 false
+func (b *tokenBucket) take(now time.Time) (time.Duration, bool) {
+	return takeAll(now, b)
+}
+
+// 补充令牌并计算取出一个令牌需要等待的时间，不修改令牌数，调用方需持有锁
+func (b *tokenBucket) reserve(now time.Time) (time.Duration, bool) {
+	burst := float64(max(b.limit.Burst, 1))
+	if b.last.IsZero() {
+		b.tokens = burst
+		b.last = now
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(burst, b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		return 0, true
+	}
+	if b.limit.Mode != RateLimitDelay || b.limit.Rate <= 0 {
+		return 0, false
+	}
+	wait := time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+	if b.limit.MaxDelay > 0 && wait > b.limit.MaxDelay {
+		return 0, false
+	}
+	return wait, true
+}
+
+// 从所有令牌桶中各取出一个令牌，任一令牌桶不允许通过时都不取出，返回最长的等待时间
+func takeAll(now time.Time, buckets ...*tokenBucket) (time.Duration, bool) {
+	for _, bucket := range buckets {
+		bucket.mu.Lock()
+		defer bucket.mu.Unlock()
+	}
+	var wait time.Duration
+	for _, bucket := range buckets {
+		bucketWait, ok := bucket.reserve(now)
+		if !ok {
+			return 0, false
+		}
+		wait = max(wait, bucketWait)
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return wait, true
+}
+
+// WithRateLimit 在创建路由器时设置限流
+func WithRateLimit[T any](scope RateLimitScope, name string, limit RateLimit) RouterOption[T] {
+	return func(r *Router[T]) {
+		r.SetRateLimit(scope, name, limit)
+	}
+}
+
+// SetRateLimit 设置 scope 范围内 name 的限流，会重置已有的令牌桶
+func (r *Router[T]) SetRateLimit(scope RateLimitScope, name string, limit RateLimit) {
+	r.rateLimits.Store(rateLimitKey{scope: scope, name: name}, &tokenBucket{limit: limit})
+}
+
+// RemoveRateLimit 移除 scope 范围内 name 的限流
+func (r *Router[T]) RemoveRateLimit(scope RateLimitScope, name string) {
+	r.rateLimits.LoadAndDelete(rateLimitKey{scope: scope, name: name})
+}
+
+// 取出 scope 范围内 name 的令牌，没有设置限流时直接通过
+func (r *Router[T]) throttle(scope RateLimitScope, name string) (time.Duration, bool) {
+	bucket, ok := r.rateLimits.Load(rateLimitKey{scope: scope, name: name})
+	if !ok {
+		return 0, true
+	}
+	return bucket.take(r.clock.Now())
+}
+
+// 按发送路由与组限流，转发包的发送路由为转发栈中的最后一个路由
+//
+// 发送路由与组的令牌同时取出，其中一个不允许通过时另一个的令牌不会被消耗
+func (r *Router[T]) admit(packet RoutePacket[T]) (time.Duration, bool) {
+	sender := packet.Header.Src
+	if len(packet.Header.Stack) > 0 {
+		sender = packet.Header.Stack[len(packet.Header.Stack)-1]
+	}
+	var buckets []*tokenBucket
+	if bucket, ok := r.rateLimits.Load(rateLimitKey{scope: RateLimitSrc, name: sender}); ok {
+		buckets = append(buckets, bucket)
+	}
+	if packet.Header.Type == RoutePacketTypeMulticast || packet.Header.Type == RoutePacketTypeAnycast {
+		if bucket, ok := r.rateLimits.Load(rateLimitKey{scope: RateLimitGroup, name: packet.Header.Dest}); ok {
+			buckets = append(buckets, bucket)
+		}
+	}
+	if len(buckets) == 0 {
+		return 0, true
+	}
+	return takeAll(r.clock.Now(), buckets...)
+}
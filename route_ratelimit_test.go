@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试令牌桶的补充、丢弃与预支
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	bucket := &tokenBucket{limit: RateLimit{Rate: 2, Burst: 2}}
+	_, ok := bucket.take(now)
+	assert.True(t, ok)
+	_, ok = bucket.take(now)
+	assert.True(t, ok)
+	_, ok = bucket.take(now)
+	assert.False(t, ok)
+	// 半秒补充一个令牌
+	_, ok = bucket.take(now.Add(500 * time.Millisecond))
+	assert.True(t, ok)
+
+	bucket = &tokenBucket{limit: RateLimit{Rate: 2, Burst: 1, Mode: RateLimitDelay, MaxDelay: time.Second}}
+	wait, ok := bucket.take(now)
+	assert.True(t, ok)
+	assert.Zero(t, wait)
+	wait, ok = bucket.take(now)
+	assert.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+	wait, ok = bucket.take(now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, wait)
+	// 超过最长等待时间后丢弃
+	_, ok = bucket.take(now)
+	assert.False(t, ok)
+}
+
+// 测试按发送路由限流，吵闹的插件不会影响其他发送者
+func TestRateLimitSrc(t *testing.T) {
+	drops := &dropRecorder{}
+	router := NewRouter[string](64,
+		WithMetrics[string](drops),
+		WithRateLimit[string](RateLimitSrc, "noisy", RateLimit{Burst: 2}),
+	)
+	go router.Run()
+	defer router.Stop()
+
+	noisy, _ := router.AddRoute("noisy")
+	quiet, _ := router.AddRoute("quiet")
+	receiver, _ := router.AddRoute("receiver")
+	var mu sync.Mutex
+	received := map[string]int{}
+	receiver.HandlerFunc(func(header RoutePacketHeader, data string) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[data]++
+	})
+
+	for i := 0; i < 5; i++ {
+		noisy.SendBroadcast("spam")
+		quiet.Send("receiver", "hello")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{"spam": 2, "hello": 5}, received)
+	assert.Equal(t, []string{":rate_limited", ":rate_limited", ":rate_limited"}, drops.Reasons())
+}
+
+// 测试按目标路由延迟投递
+func TestRateLimitDestDelay(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	router := NewRouter[string](64, WithClock[string](clock))
+	router.SetRateLimit(RateLimitDest, "adapter", RateLimit{Rate: 1, Burst: 1, Mode: RateLimitDelay})
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	adapter, _ := router.AddRoute("adapter")
+	var mu sync.Mutex
+	var received []string
+	adapter.HandlerFunc(func(header RoutePacketHeader, data string) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, data)
+	})
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received)
+	}
+
+	sender.Send("adapter", "1")
+	sender.Send("adapter", "2")
+	sender.Send("adapter", "3")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, count())
+
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool { return count() == 2 }, time.Second, 10*time.Millisecond)
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool { return count() == 3 }, time.Second, 10*time.Millisecond)
+
+	// 移除限流后立即投递
+	router.RemoveRateLimit(RateLimitDest, "adapter")
+	sender.Send("adapter", "4")
+	assert.Eventually(t, func() bool { return count() == 4 }, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"1", "2", "3", "4"}, received)
+}
+
+// 测试按组限流
+func TestRateLimitGroup(t *testing.T) {
+	drops := &dropRecorder{}
+	router := NewRouter[string](64,
+		WithMetrics[string](drops),
+		WithRateLimit[string](RateLimitGroup, "group", RateLimit{Burst: 1}),
+	)
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	member, _ := router.AddRoute("member")
+	assert.NoError(t, member.JoinGroup("group"))
+	var count atomic.Int32
+	member.HandlerFunc(func(header RoutePacketHeader, data string) {
+		count.Add(1)
+	})
+
+	sender.SendGroup("group", "first")
+	sender.SendGroup("group", "second")
+	sender.SendAnycast("group", "third")
+	// 单播不受组限流影响
+	sender.Send("member", "direct")
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, int32(2), count.Load())
+	assert.Equal(t, []string{"group:rate_limited", "group:rate_limited"}, drops.Reasons())
+}
+
+// 测试组限流拒绝时不消耗发送路由的令牌
+func TestRateLimitGroupKeepsSrcToken(t *testing.T) {
+	router := NewRouter[string](64,
+		WithSynchronousDelivery[string](),
+		WithClock[string](NewManualClock(time.Unix(0, 0))),
+		WithRateLimit[string](RateLimitSrc, "sender", RateLimit{Burst: 2}),
+		WithRateLimit[string](RateLimitGroup, "group", RateLimit{Burst: 1}),
+	)
+	sender, _ := router.AddRoute("sender")
+	member, _ := router.AddRoute("member")
+	assert.NoError(t, member.JoinGroup("group"))
+	var received []string
+	member.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received = append(received, data)
+	})
+
+	sender.SendGroup("group", "first")
+	sender.SendGroup("group", "rejected")
+	// 发送路由仍有一个令牌
+	sender.Send("member", "direct")
+	router.Flush()
+	assert.Equal(t, []string{"first", "direct"}, received)
+}
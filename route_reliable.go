@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ReliableHandler 需要确认的处理器，返回 nil 表示确认，返回错误时可靠包会被重新投递
+type ReliableHandler[T any] func(header RoutePacketHeader, data T) error
+
+// ReliableHandlerFunc 设置需要确认的处理器，处理器 panic 视为处理失败
+func (r *Route[T]) ReliableHandlerFunc(handler ReliableHandler[T]) {
+	r.handler = handler
+}
+
+// SendReliable 发送可靠单播包，处理器未确认时按重试策略重新投递
+func (r *Route[T]) SendReliable(dest string, message T) {
+	r.router.enqueue(RoutePacket[T]{
+		Header: RoutePacketHeader{
+			Type:     RoutePacketTypeUnicast,
+			Src:      r.name,
+			Dest:     dest,
+			Stack:    []string{r.name},
+			Ttl:      r.router.defaultTtl,
+			Reliable: true,
+		},
+		Data: message,
+	})
+}
+
+// SendGroupReliable 发送可靠组播包，每个成员独立确认与重试
+func (r *Route[T]) SendGroupReliable(group string, message T) {
+	r.router.enqueue(RoutePacket[T]{
+		Header: RoutePacketHeader{
+			Type:     RoutePacketTypeMulticast,
+			Src:      r.name,
+			Dest:     group,
+			Stack:    []string{r.name},
+			Ttl:      r.router.defaultTtl,
+			Reliable: true,
+		},
+		Data: message,
+	})
+}
+
+// RetryPolicy 可靠包的重试策略
+type RetryPolicy struct {
+	MaxAttempts    int           // 最大投递次数（包含首次投递）
+	InitialBackoff time.Duration // 首次重试前的等待时间
+	MaxBackoff     time.Duration // 等待时间上限
+	Multiplier     float64       // 每次重试等待时间的倍数
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+}
+
+// 第 attempt 次投递失败后的等待时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= p.Multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(backoff)
+}
+
+// WithRetryPolicy 设置可靠包的重试策略
+func WithRetryPolicy[T any](policy RetryPolicy) RouterOption[T] {
+	return func(r *Router[T]) {
+		if policy.MaxAttempts <= 0 {
+			policy.MaxAttempts = 1
+		}
+		if policy.Multiplier < 1 {
+			policy.Multiplier = 1
+		}
+		r.retryPolicy = policy
+	}
+}
+
+// DeadLetter 无法投递的可靠包
+type DeadLetter[T any] struct {
+	Packet   RoutePacket[T]
+	Route    string // 投递目标，组播时为具体成员
+	Reason   string // 丢弃原因
+	Err      error  // 最后一次处理失败或写入日志失败的错误
+	Attempts int    // 已投递次数
+}
+
+// WithDeadLetter 设置死信处理函数，可靠包最终无法投递时调用
+func WithDeadLetter[T any](handler func(letter DeadLetter[T])) RouterOption[T] {
+	return func(r *Router[T]) {
+		r.deadLetter = handler
+	}
+}
+
+// DeadLetterQueue 有容量上限的内存死信队列，超出容量时丢弃最早的死信
+type DeadLetterQueue[T any] struct {
+	mu      sync.Mutex
+	size    int
+	letters []DeadLetter[T]
+}
+
+func NewDeadLetterQueue[T any](size int) *DeadLetterQueue[T] {
+	return &DeadLetterQueue[T]{size: size}
+}
+
+// Push 加入死信，可直接作为 WithDeadLetter 的处理函数
+func (q *DeadLetterQueue[T]) Push(letter DeadLetter[T]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters = append(q.letters, letter)
+	if q.size > 0 && len(q.letters) > q.size {
+		q.letters = q.letters[len(q.letters)-q.size:]
+	}
+}
+
+// Len 返回死信数量
+func (q *DeadLetterQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.letters)
+}
+
+// Drain 取出并清空所有死信
+func (q *DeadLetterQueue[T]) Drain() []DeadLetter[T] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	result := q.letters
+	q.letters = nil
+	return result
+}
+
+// 丢弃包，可靠包同时进入死信，请求包通知请求方失败
+func (r *Router[T]) drop(packet RoutePacket[T], dest string, reason string) {
+	r.dropError(packet, dest, reason, nil)
+}
+
+// 丢弃包并在死信中记录导致丢弃的错误
+func (r *Router[T]) dropError(packet RoutePacket[T], dest string, reason string, err error) {
+	r.metrics.PacketDropped(packet.Header.Type, dest, reason)
+	r.failRequest(packet, reason)
+	if packet.Header.Reliable && r.deadLetter != nil {
+		r.deadLetter(DeadLetter[T]{
+			Packet: packet,
+			Route:  dest,
+			Reason: reason,
+			Err:    err,
+		})
+	}
+}
+
+// 调用处理器，可靠包的 panic 转换为错误
+func (r *Router[T]) invoke(route *Route[T], header RoutePacketHeader, data T) (err error) {
+	if header.Reliable {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("handler panic: %v", recovered)
+			}
+		}()
+	}
+	return route.handler(header, data)
+}
+
+// 处理失败后按重试策略重新投递，超过次数后进入死信
+func (r *Router[T]) retry(name string, packet RoutePacket[T], attempt int, err error) {
+	if attempt >= r.retryPolicy.MaxAttempts {
+		defer packet.delivery.done()
+		r.metrics.PacketDropped(packet.Header.Type, name, DropReasonUnacked)
+		if r.deadLetter != nil {
+			r.deadLetter(DeadLetter[T]{
+				Packet:   packet,
+				Route:    name,
+				Reason:   DropReasonUnacked,
+				Err:      err,
+				Attempts: attempt,
+			})
+		}
+		return
+	}
+	r.scheduler.schedule(r.clock.Now().Add(r.retryPolicy.backoff(attempt)), func() {
+		route, ok := r.routes.Load(name)
+		if !ok || route.handler == nil {
+			r.drop(packet, name, DropReasonNoRoute)
+			packet.delivery.done()
+			return
+		}
+		r.deliverAttempt(route, packet, attempt+1)
+	})
+}
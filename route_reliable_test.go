@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     20 * time.Millisecond,
+	Multiplier:     2,
+}
+
+// 测试处理失败后重试直到确认
+func TestReliableRetry(t *testing.T) {
+	dlq := NewDeadLetterQueue[string](10)
+	router := NewRouter[string](64, WithRetryPolicy[string](testRetryPolicy), WithDeadLetter(dlq.Push))
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	receiver, _ := router.AddRoute("receiver")
+
+	var attempts atomic.Int32
+	acked := make(chan struct{})
+	receiver.ReliableHandlerFunc(func(header RoutePacketHeader, data string) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("temporary failure")
+		}
+		close(acked)
+		return nil
+	})
+
+	sender.SendReliable("receiver", "charge")
+	select {
+	case <-acked:
+	case <-time.After(time.Second):
+		t.Fatal("packet not acked")
+	}
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, 0, dlq.Len())
+}
+
+// 测试重试耗尽后进入死信，panic 视为失败
+func TestReliableDeadLetter(t *testing.T) {
+	letters := make(chan DeadLetter[string], 4)
+	router := NewRouter[string](64, WithRetryPolicy[string](testRetryPolicy), WithDeadLetter(func(letter DeadLetter[string]) {
+		letters <- letter
+	}))
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	receiver, _ := router.AddRoute("receiver")
+	var attempts atomic.Int32
+	receiver.ReliableHandlerFunc(func(header RoutePacketHeader, data string) error {
+		attempts.Add(1)
+		panic("boom")
+	})
+
+	sender.SendReliable("receiver", "charge")
+	letter := <-letters
+	assert.Equal(t, "receiver", letter.Route)
+	assert.Equal(t, DropReasonUnacked, letter.Reason)
+	assert.Equal(t, 3, letter.Attempts)
+	assert.ErrorContains(t, letter.Err, "boom")
+	assert.Equal(t, "charge", letter.Packet.Data)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// 无法路由的可靠包直接进入死信
+	sender.SendReliable("nobody", "lost")
+	letter = <-letters
+	assert.Equal(t, "nobody", letter.Route)
+	assert.Equal(t, DropReasonNoRoute, letter.Reason)
+}
+
+// 测试可靠组播中每个成员独立确认
+func TestReliableMulticast(t *testing.T) {
+	dlq := NewDeadLetterQueue[string](10)
+	router := NewRouter[string](64, WithRetryPolicy[string](testRetryPolicy), WithDeadLetter(dlq.Push))
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	good, _ := router.AddRoute("good")
+	bad, _ := router.AddRoute("bad")
+	assert.NoError(t, good.JoinGroup("billing"))
+	assert.NoError(t, bad.JoinGroup("billing"))
+
+	var goodCount, badCount atomic.Int32
+	good.ReliableHandlerFunc(func(header RoutePacketHeader, data string) error {
+		goodCount.Add(1)
+		return nil
+	})
+	bad.ReliableHandlerFunc(func(header RoutePacketHeader, data string) error {
+		badCount.Add(1)
+		return errors.New("always fails")
+	})
+
+	sender.SendGroupReliable("billing", "invoice")
+	assert.Eventually(t, func() bool {
+		return dlq.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	letters := dlq.Drain()
+	assert.Equal(t, "bad", letters[0].Route)
+	assert.Equal(t, int32(1), goodCount.Load())
+	assert.Equal(t, int32(3), badCount.Load())
+	assert.Equal(t, 0, dlq.Len())
+}
+
+// 测试重试等待时间
+func TestRetryPolicyBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Millisecond, testRetryPolicy.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, testRetryPolicy.backoff(2))
+	assert.Equal(t, 20*time.Millisecond, testRetryPolicy.backoff(5))
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 请求的应答或失败原因
+type requestResult[T any] struct {
+	data T
+	err  error
+}
+
+// Request 发送请求包并等待目标路由应答，超时与取消由 ctx 控制，请求包被丢弃时立即返回错误
+//
+// 同步投递模式下 Request 调用 Flush 处理请求，Flush 结束时仍未应答（例如应答被延迟）则返回错误
+func (r *Route[T]) Request(ctx context.Context, dest string, message T) (T, error) {
+	var zero T
+	id := fmt.Sprintf("%s#%d", r.name, r.router.sequence.Add(1))
+	reply := make(chan requestResult[T], 1)
+	r.router.pending.Store(id, reply)
+	defer r.router.pending.LoadAndDelete(id)
+
+	trace, _ := TraceFromContext(ctx)
+	r.router.enqueue(RoutePacket[T]{
+		Header: RoutePacketHeader{
+			Type:     RoutePacketTypeUnicast,
+			Src:      r.name,
+			Dest:     dest,
+			Stack:    []string{r.name},
+			Ttl:      r.router.defaultTtl,
+			ID:       id,
+			Trace:    trace,
+			Priority: priorityFromContext(ctx),
+		},
+		Data: message,
+	})
+
+	if r.router.synchronous {
+		// 同步模式下没有协程处理队列，在当前协程中处理后应答必须已经到达
+		r.router.Flush()
+		select {
+		case result := <-reply:
+			return result.data, result.err
+		default:
+			return zero, errors.New("request not answered during flush")
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-r.router.done:
+		return zero, errors.New("router stopped")
+	case result := <-reply:
+		return result.data, result.err
+	}
+}
+
+// Reply 应答请求包，header 为处理器收到的请求包头
+func (r *Route[T]) Reply(header RoutePacketHeader, message T) error {
+	if header.ID == "" {
+		return errors.New("packet is not a request")
+	}
+	r.router.enqueue(RoutePacket[T]{
+		Header: RoutePacketHeader{
+			Type:     RoutePacketTypeReply,
+			Src:      r.name,
+			Dest:     header.Src,
+			Stack:    []string{r.name},
+			Ttl:      r.router.defaultTtl,
+			ID:       header.ID,
+			Trace:    header.Trace,
+			Priority: PriorityHigh,
+		},
+		Data: message,
+	})
+	return nil
+}
+
+// 处理应答消息，无人等待的应答直接丢弃
+func (r *Router[T]) handleReply(packet RoutePacket[T]) {
+	if reply, ok := r.pending.LoadAndDelete(packet.Header.ID); ok {
+		select {
+		case reply <- requestResult[T]{data: packet.Data}:
+		default:
+		}
+		return
+	}
+	r.drop(packet, packet.Header.Dest, DropReasonNoPending)
+}
+
+// 请求包被丢弃时通知等待的请求方
+func (r *Router[T]) failRequest(packet RoutePacket[T], reason string) {
+	if packet.Header.ID == "" || packet.Header.Type == RoutePacketTypeReply {
+		return
+	}
+	if reply, ok := r.pending.LoadAndDelete(packet.Header.ID); ok {
+		select {
+		case reply <- requestResult[T]{err: fmt.Errorf("request dropped: %s", reason)}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试请求应答
+func TestRequestReply(t *testing.T) {
+	router := NewRouter[string](64)
+	go router.Run()
+	defer router.Stop()
+
+	client, _ := router.AddRoute("client")
+	server, _ := router.AddRoute("server")
+	server.HandlerFunc(func(header RoutePacketHeader, data string) {
+		assert.NotEmpty(t, header.ID)
+		assert.NoError(t, server.Reply(header, "pong:"+data))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := client.Request(ctx, "server", "ping")
+	assert.NoError(t, err)
+	assert.Equal(t, "pong:ping", resp)
+
+	// 非请求包不能应答
+	assert.Error(t, server.Reply(RoutePacketHeader{Src: "client"}, "oops"))
+}
+
+// 测试转发后的请求由最终处理者应答
+func TestRequestForwardReply(t *testing.T) {
+	router := NewRouter[string](64)
+	go router.Run()
+	defer router.Stop()
+
+	client, _ := router.AddRoute("client")
+	proxy, _ := router.AddRoute("proxy")
+	server, _ := router.AddRoute("server")
+	proxy.HandlerFunc(func(header RoutePacketHeader, data string) {
+		proxy.SendForward("server", &header, data)
+	})
+	server.HandlerFunc(func(header RoutePacketHeader, data string) {
+		assert.Equal(t, []string{"client", "proxy"}, header.Stack)
+		assert.NoError(t, server.Reply(header, "done"))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := client.Request(ctx, "proxy", "job")
+	assert.NoError(t, err)
+	assert.Equal(t, "done", resp)
+}
+
+// 测试请求超时
+func TestRequestTimeout(t *testing.T) {
+	router := NewRouter[string](64)
+	go router.Run()
+	defer router.Stop()
+
+	client, _ := router.AddRoute("client")
+	silent, _ := router.AddRoute("silent")
+	silent.HandlerFunc(func(header RoutePacketHeader, data string) {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Request(ctx, "silent", "hello")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, router.pending.Len())
+}
+
+// 测试请求包被丢弃时立即失败
+func TestRequestDropped(t *testing.T) {
+	router := NewRouter[string](64)
+	go router.Run()
+	defer router.Stop()
+
+	client, _ := router.AddRoute("client")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Request(ctx, "missing", "hello")
+	assert.EqualError(t, err, "request dropped: no_route")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 0, router.pending.Len())
+}
@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// 定时任务
+type scheduledTask struct {
+	at    time.Time
+	seq   uint64 // 相同时间按加入顺序触发
+	fire  func()
+	index int // 在堆中的位置，-1 表示已出堆
+}
+
+type taskHeap []*scheduledTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x any) {
+	task := x.(*scheduledTask)
+	task.index = len(*h)
+	*h = append(*h, task)
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	task.index = -1
+	*h = old[:n-1]
+	return task
+}
+
+// 基于最小堆的定时器，所有任务共用一个协程
+type scheduler struct {
+	clock Clock
+	mu    sync.Mutex
+	tasks taskHeap
+	seq   uint64
+	wake  chan struct{}
+}
+
+func newScheduler(clock Clock) *scheduler {
+	return &scheduler{
+		clock: clock,
+		tasks: make(taskHeap, 0),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+func (s *scheduler) schedule(at time.Time, fire func()) *ScheduleHandle {
+	s.mu.Lock()
+	s.seq++
+	task := &scheduledTask{at: at, seq: s.seq, fire: fire}
+	heap.Push(&s.tasks, task)
+	earliest := s.tasks[0] == task
+	s.mu.Unlock()
+	if earliest {
+		// 新任务最早到期，唤醒调度协程重新计算等待时间
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return &ScheduleHandle{scheduler: s, task: task}
+}
+
+func (s *scheduler) cancel(task *scheduledTask) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task.index < 0 {
+		return false
+	}
+	heap.Remove(&s.tasks, task.index)
+	return true
+}
+
+// 取出所有到期任务并返回下一次唤醒的通道
+func (s *scheduler) due() ([]*scheduledTask, <-chan time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := s.popExpired()
+	if len(s.tasks) == 0 {
+		return result, nil
+	}
+	return result, s.clock.Until(s.tasks[0].at)
+}
+
+// 取出所有到期任务，不等待后续任务
+func (s *scheduler) expired() []*scheduledTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.popExpired()
+}
+
+func (s *scheduler) popExpired() []*scheduledTask {
+	now := s.clock.Now()
+	var result []*scheduledTask
+	for len(s.tasks) > 0 && !s.tasks[0].at.After(now) {
+		result = append(result, heap.Pop(&s.tasks).(*scheduledTask))
+	}
+	return result
+}
+
+func (s *scheduler) run(ctx context.Context) {
+	for {
+		tasks, next := s.due()
+		for _, task := range tasks {
+			task.fire()
+		}
+		if len(tasks) > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-next:
+		}
+	}
+}
+
+// ScheduleHandle 定时发送的句柄
+type ScheduleHandle struct {
+	scheduler *scheduler
+	task      *scheduledTask
+}
+
+// At 返回计划发送的时间
+func (h *ScheduleHandle) At() time.Time {
+	return h.task.at
+}
+
+// Cancel 取消尚未发送的包，已发送或已取消时返回 false
+func (h *ScheduleHandle) Cancel() bool {
+	return h.scheduler.cancel(h.task)
+}
+
+// SendAt 在指定时间发送单播包
+func (r *Route[T]) SendAt(at time.Time, dest string, message T) *ScheduleHandle {
+	packet := RoutePacket[T]{
+		Header: RoutePacketHeader{
+			Type:  RoutePacketTypeUnicast,
+			Src:   r.name,
+			Dest:  dest,
+			Stack: []string{r.name},
+			Ttl:   r.router.defaultTtl,
+		},
+		Data: message,
+	}
+	return r.router.scheduler.schedule(at, func() {
+		r.router.enqueue(packet)
+	})
+}
+
+// SendAfter 在指定时长后发送单播包
+func (r *Route[T]) SendAfter(d time.Duration, dest string, message T) *ScheduleHandle {
+	return r.SendAt(r.router.clock.Now().Add(d), dest, message)
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试延迟发送
+func TestSendAfter(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	router := NewRouter[string](64, WithClock[string](clock))
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	receiver, _ := router.AddRoute("receiver")
+	received := make(chan string, 4)
+	receiver.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received <- data
+	})
+
+	sender.SendAfter(10*time.Second, "receiver", "late")
+	sender.SendAt(clock.Now().Add(5*time.Second), "receiver", "early")
+
+	clock.Advance(4 * time.Second)
+	assertNoReceive(t, received)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, "early", <-received)
+	assertNoReceive(t, received)
+
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, "late", <-received)
+}
+
+// 测试取消定时发送
+func TestSendAfterCancel(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	router := NewRouter[string](64, WithClock[string](clock))
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	receiver, _ := router.AddRoute("receiver")
+	var count atomic.Int32
+	receiver.HandlerFunc(func(header RoutePacketHeader, data string) {
+		count.Add(1)
+	})
+
+	handle := sender.SendAfter(time.Second, "receiver", "cancelled")
+	assert.Equal(t, clock.Now().Add(time.Second), handle.At())
+	assert.True(t, handle.Cancel())
+	assert.False(t, handle.Cancel())
+
+	clock.Advance(2 * time.Second)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), count.Load())
+}
+
+// 测试系统时钟下定时发送按顺序到达
+func TestSendAtSystemClock(t *testing.T) {
+	router := NewRouter[string](64)
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	receiver, _ := router.AddRoute("receiver")
+	received := make(chan string, 3)
+	receiver.HandlerFunc(func(header RoutePacketHeader, data string) {
+		received <- data
+	})
+
+	now := time.Now()
+	sender.SendAt(now.Add(60*time.Millisecond), "receiver", "third")
+	sender.SendAt(now.Add(20*time.Millisecond), "receiver", "first")
+	sender.SendAt(now.Add(40*time.Millisecond), "receiver", "second")
+
+	assert.Equal(t, "first", <-received)
+	assert.Equal(t, "second", <-received)
+	assert.Equal(t, "third", <-received)
+}
+
+func assertNoReceive[T any](t *testing.T, ch <-chan T) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		t.Errorf("unexpected receive: %v", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,38 @@
+package bot
+
+// WithSynchronousDelivery 同步投递模式，用于测试
+//
+// 此模式下不调用 Run，而是通过 Flush 在当前协程中依次处理包并调用处理器，
+// 定时发送、重试与限流延迟在 Flush 时按时钟触发；队列没有容量限制，发送不会阻塞；
+// Request 会自行调用 Flush 处理请求与应答
+func WithSynchronousDelivery[T any]() RouterOption[T] {
+	return func(r *Router[T]) {
+		r.synchronous = true
+		r.lanes.unbounded = true
+	}
+}
+
+// Flush 触发到期的定时任务并处理队列中的所有包，直到没有可处理的包为止，返回处理的包数量
+//
+// 不能与 Run 同时使用；处理器中发送的包会在同一次 Flush 中继续处理
+func (r *Router[T]) Flush() int {
+	if r.journal != nil {
+		r.replayOnce.Do(r.replay)
+	}
+	count := 0
+	for {
+		tasks := r.scheduler.expired()
+		for _, task := range tasks {
+			task.fire()
+		}
+		packet, ok := r.lanes.poll()
+		if !ok {
+			if len(tasks) == 0 {
+				return count
+			}
+			continue
+		}
+		r.process(packet)
+		count++
+	}
+}
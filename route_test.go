@@ -337,3 +337,117 @@ func TestConcurrentOperations(t *testing.T) {
 	// 发送 80 次广播 ，广播不会传播到自身 (10 个路由发送 80 次，每次有 9 个其他路由接收到广播)
 	assert.Equal(t, 720, int(count.Load()))
 }
+
+type dropRecorder struct {
+	noopMetrics
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (d *dropRecorder) PacketDropped(packetType RoutePacketType, dest string, reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reasons = append(d.reasons, dest+":"+reason)
+}
+
+func (d *dropRecorder) Reasons() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.reasons...)
+}
+
+// 测试环路检测
+func TestLoopDetection(t *testing.T) {
+	drops := &dropRecorder{}
+	router := NewRouter[string](64, WithLoopDetection[string](0), WithMetrics[string](drops))
+	go router.Run()
+	defer router.Stop()
+
+	routeA, _ := router.AddRoute("A")
+	routeB, _ := router.AddRoute("B")
+
+	var aCount, bCount atomic.Int32
+	// A 与 B 互相转发，形成 A->B->A 的环路
+	routeA.HandlerFunc(func(header RoutePacketHeader, data string) {
+		aCount.Add(1)
+		routeA.SendForward("B", &header, data)
+	})
+	routeB.HandlerFunc(func(header RoutePacketHeader, data string) {
+		bCount.Add(1)
+		routeB.SendForward("A", &header, data)
+	})
+
+	routeA.Send("B", "ping")
+	time.Sleep(100 * time.Millisecond)
+
+	// B 收到后转发回 A 时被拒绝
+	assert.Equal(t, int32(0), aCount.Load())
+	assert.Equal(t, int32(1), bCount.Load())
+	assert.Equal(t, []string{"A:loop"}, drops.Reasons())
+}
+
+// 测试转发栈深度限制
+func TestMaxStackDepth(t *testing.T) {
+	drops := &dropRecorder{}
+	router := NewRouter[string](64, WithLoopDetection[string](3), WithMetrics[string](drops))
+	go router.Run()
+	defer router.Stop()
+
+	names := []string{"A", "B", "C", "D", "E"}
+	routes := make(map[string]*Route[string])
+	for _, name := range names {
+		routes[name], _ = router.AddRoute(name)
+	}
+	var received sync.Map
+	for i, name := range names[1:] {
+		next := ""
+		if i+2 < len(names) {
+			next = names[i+2]
+		}
+		route := routes[name]
+		route.HandlerFunc(func(header RoutePacketHeader, data string) {
+			received.Store(name, len(header.Stack))
+			if next != "" {
+				route.SendForward(next, &header, data)
+			}
+		})
+	}
+
+	routes["A"].Send("B", "deep")
+	time.Sleep(100 * time.Millisecond)
+
+	_, ok := received.Load("D")
+	assert.True(t, ok)
+	_, ok = received.Load("E")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"E:stack_depth"}, drops.Reasons())
+}
+
+// 测试组播转发时跳过转发栈中的成员
+func TestLoopDetectionMulticast(t *testing.T) {
+	router := NewRouter[string](64, WithLoopDetection[string](0))
+	go router.Run()
+	defer router.Stop()
+
+	sender, _ := router.AddRoute("sender")
+	relay, _ := router.AddRoute("relay")
+	member, _ := router.AddRoute("member")
+	assert.NoError(t, relay.JoinGroup("group"))
+	assert.NoError(t, member.JoinGroup("group"))
+
+	var relayCount, memberCount atomic.Int32
+	relay.HandlerFunc(func(header RoutePacketHeader, data string) {
+		relayCount.Add(1)
+		relay.SendForward("group", &header, data)
+	})
+	member.HandlerFunc(func(header RoutePacketHeader, data string) {
+		memberCount.Add(1)
+	})
+
+	// relay 转发回组内时不会再收到自己转发的包
+	sender.SendGroup("group", "fanout")
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, int32(1), relayCount.Load())
+	assert.Equal(t, int32(2), memberCount.Load())
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/greek-milk-bot/core/utils"
+)
+
+type TopologyEventType uint8
+
+const (
+	TopologyRouteAdded TopologyEventType = iota
+	TopologyRouteRemoved
+	TopologyGroupJoined
+	TopologyGroupLeft
+)
+
+func (t TopologyEventType) String() string {
+	switch t {
+	case TopologyRouteAdded:
+		return "route_added"
+	case TopologyRouteRemoved:
+		return "route_removed"
+	case TopologyGroupJoined:
+		return "group_joined"
+	case TopologyGroupLeft:
+		return "group_left"
+	}
+	return "unknown"
+}
+
+// TopologyEvent 拓扑变化事件
+type TopologyEvent struct {
+	Type  TopologyEventType
+	Route string
+	Group string // 仅组事件有效
+}
+
+// FilterInfo 过滤器注册信息
+type FilterInfo struct {
+	Pattern string // 过滤的目标
+	Route   string // 注册过滤器的路由
+	Count   int    // 过滤器数量
+}
+
+// Routes 返回所有路由名称
+func (r *Router[T]) Routes() []string {
+	result := make([]string, 0)
+	r.routes.Range(func(name string, _ *Route[T]) bool {
+		result = append(result, name)
+		return true
+	})
+	sort.Strings(result)
+	return result
+}
+
+// Groups 返回所有非空组名称
+func (r *Router[T]) Groups() []string {
+	result := make([]string, 0)
+	r.groups.Range(func(name string, _ mapset.Set[string]) bool {
+		result = append(result, name)
+		return true
+	})
+	sort.Strings(result)
+	return result
+}
+
+// Members 返回组内的成员，组不存在时返回空
+func (r *Router[T]) Members(group string) []string {
+	groupSet, ok := r.groups.Load(group)
+	if !ok {
+		return []string{}
+	}
+	result := groupSet.ToSlice()
+	sort.Strings(result)
+	return result
+}
+
+// Filters 返回已注册的过滤器
+func (r *Router[T]) Filters() []FilterInfo {
+	result := make([]FilterInfo, 0)
+	r.filter.Range(func(pattern string, inner *utils.Map[string, *utils.Array[*Filter[T]]]) bool {
+		inner.Range(func(name string, filters *utils.Array[*Filter[T]]) bool {
+			if count := filters.Len(); count > 0 {
+				result = append(result, FilterInfo{
+					Pattern: pattern,
+					Route:   name,
+					Count:   count,
+				})
+			}
+			return true
+		})
+		return true
+	})
+	slices.SortFunc(result, func(a, b FilterInfo) int {
+		return cmp.Or(cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Route, b.Route))
+	})
+	return result
+}
+
+// WatchTopology 订阅拓扑变化事件，返回取消订阅的函数
+//
+// 回调在触发变化的协程中同步执行，不应长时间阻塞
+func (r *Router[T]) WatchTopology(watcher func(event TopologyEvent)) (cancel func()) {
+	id := r.watcherSeq.Add(1)
+	r.watchers.Store(id, watcher)
+	return func() {
+		r.watchers.LoadAndDelete(id)
+	}
+}
+
+func (r *Router[T]) emitTopology(event TopologyEvent) {
+	r.watchers.Range(func(_ uint64, watcher func(event TopologyEvent)) bool {
+		watcher(event)
+		return true
+	})
+}
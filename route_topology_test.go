@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试拓扑快照
+func TestTopologySnapshot(t *testing.T) {
+	router := NewRouter[string](64)
+
+	route1, _ := router.AddRoute("route1")
+	route2, _ := router.AddRoute("route2")
+	assert.NoError(t, route1.JoinGroup("group1"))
+	assert.NoError(t, route2.JoinGroup("group1"))
+	assert.NoError(t, route2.JoinGroup("group2"))
+
+	filter := Filter[string](func(header RoutePacketHeader, data string) bool {
+		return false
+	})
+	assert.NoError(t, route1.AddFilter("route2", &filter))
+
+	assert.Equal(t, []string{"route1", "route2"}, router.Routes())
+	assert.Equal(t, []string{"group1", "group2"}, router.Groups())
+	assert.Equal(t, []string{"route1", "route2"}, router.Members("group1"))
+	assert.Equal(t, []string{"route2"}, router.Members("group2"))
+	assert.Empty(t, router.Members("nonexistent"))
+	assert.Equal(t, []FilterInfo{{Pattern: "route2", Route: "route1", Count: 1}}, router.Filters())
+
+	assert.NoError(t, router.RemoveRoute("route2"))
+	assert.Equal(t, []string{"route1"}, router.Routes())
+	assert.Equal(t, []string{"group1"}, router.Groups())
+}
+
+// 测试拓扑变化订阅
+func TestWatchTopology(t *testing.T) {
+	router := NewRouter[string](64)
+
+	var mu sync.Mutex
+	var events []TopologyEvent
+	cancel := router.WatchTopology(func(event TopologyEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+
+	route1, _ := router.AddRoute("route1")
+	_, err := router.AddRoute("route1")
+	assert.Error(t, err)
+	assert.NoError(t, route1.JoinGroup("group1"))
+	assert.NoError(t, route1.JoinGroup("group1"))
+	route1.LeaveGroup("group1")
+	route1.LeaveGroup("group1")
+	assert.NoError(t, route1.JoinGroup("group2"))
+	assert.NoError(t, router.RemoveRoute("route1"))
+
+	assert.Equal(t, []TopologyEvent{
+		{Type: TopologyRouteAdded, Route: "route1"},
+		{Type: TopologyGroupJoined, Route: "route1", Group: "group1"},
+		{Type: TopologyGroupLeft, Route: "route1", Group: "group1"},
+		{Type: TopologyGroupJoined, Route: "route1", Group: "group2"},
+		{Type: TopologyGroupLeft, Route: "route1", Group: "group2"},
+		{Type: TopologyRouteRemoved, Route: "route1"},
+	}, events)
+
+	cancel()
+	_, _ = router.AddRoute("route2")
+	assert.Len(t, events, 6)
+}
@@ -0,0 +1,163 @@
+// Package routertest 提供确定性的路由器测试工具
+//
+// Harness 创建的路由器使用同步投递与手动时钟，包只在 Flush、Advance
+// 或断言时处理，处理器按队列顺序在测试协程中依次执行。
+package routertest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	bot "github.com/greek-milk-bot/core"
+)
+
+// ManualClock 手动推进的时钟
+type ManualClock = bot.ManualClock
+
+// NewManualClock 创建从 start 开始的手动时钟
+func NewManualClock(start time.Time) *ManualClock {
+	return bot.NewManualClock(start)
+}
+
+// Matcher 匹配投递的包
+type Matcher[T any] func(header bot.RoutePacketHeader, data T) bool
+
+// Any 匹配任意包
+func Any[T any]() Matcher[T] {
+	return func(bot.RoutePacketHeader, T) bool {
+		return true
+	}
+}
+
+// Data 匹配数据与 want 相等的包
+func Data[T comparable](want T) Matcher[T] {
+	return func(_ bot.RoutePacketHeader, data T) bool {
+		return data == want
+	}
+}
+
+// From 匹配由 src 发出的包
+func From[T any](src string) Matcher[T] {
+	return func(header bot.RoutePacketHeader, _ T) bool {
+		return header.Src == src
+	}
+}
+
+// Delivery 一次投递记录
+type Delivery[T any] struct {
+	Route  string
+	Header bot.RoutePacketHeader
+	Data   T
+}
+
+// Harness 确定性路由器测试工具
+type Harness[T any] struct {
+	t      testing.TB
+	Router *bot.Router[T]
+	Clock  *ManualClock
+
+	mu         sync.Mutex
+	deliveries []Delivery[T] // 尚未被断言消费的投递
+}
+
+// New 创建使用同步投递与手动时钟的路由器，时钟从 Unix 零点开始
+func New[T any](t testing.TB, opts ...bot.RouterOption[T]) *Harness[T] {
+	clock := NewManualClock(time.Unix(0, 0))
+	options := append([]bot.RouterOption[T]{
+		bot.WithClock[T](clock),
+		bot.WithSynchronousDelivery[T](),
+	}, opts...)
+	return &Harness[T]{
+		t:      t,
+		Router: bot.NewRouter[T](64, options...),
+		Clock:  clock,
+	}
+}
+
+// AddRoute 添加记录所有投递的路由
+func (h *Harness[T]) AddRoute(name string) *bot.Route[T] {
+	h.t.Helper()
+	return h.Handle(name, nil)
+}
+
+// Handle 添加路由并在记录投递后调用 handler，handler 返回错误时视为未确认
+func (h *Harness[T]) Handle(name string, handler bot.ReliableHandler[T]) *bot.Route[T] {
+	h.t.Helper()
+	route, err := h.Router.AddRoute(name)
+	if err != nil {
+		h.t.Fatalf("routertest: %v", err)
+	}
+	route.ReliableHandlerFunc(func(header bot.RoutePacketHeader, data T) error {
+		h.mu.Lock()
+		h.deliveries = append(h.deliveries, Delivery[T]{Route: name, Header: header, Data: data})
+		h.mu.Unlock()
+		if handler != nil {
+			return handler(header, data)
+		}
+		return nil
+	})
+	return route
+}
+
+// Flush 处理所有已到期的包
+func (h *Harness[T]) Flush() int {
+	return h.Router.Flush()
+}
+
+// Advance 推进时钟并处理到期的包
+func (h *Harness[T]) Advance(d time.Duration) int {
+	h.Clock.Advance(d)
+	return h.Router.Flush()
+}
+
+// Deliveries 返回尚未被断言消费的投递
+func (h *Harness[T]) Deliveries() []Delivery[T] {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Delivery[T](nil), h.deliveries...)
+}
+
+// ExpectDelivered 断言 route 收到了匹配的包，返回并消费最早匹配的一次投递
+func (h *Harness[T]) ExpectDelivered(route string, matcher Matcher[T]) Delivery[T] {
+	h.t.Helper()
+	h.Flush()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, delivery := range h.deliveries {
+		if delivery.Route == route && matcher(delivery.Header, delivery.Data) {
+			h.deliveries = append(h.deliveries[:i], h.deliveries[i+1:]...)
+			return delivery
+		}
+	}
+	h.t.Errorf("routertest: expected delivery to %s, got %s", route, h.describe(route))
+	return Delivery[T]{}
+}
+
+// ExpectNoDelivery 断言 route 没有尚未消费的投递
+func (h *Harness[T]) ExpectNoDelivery(route string) {
+	h.t.Helper()
+	h.Flush()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, delivery := range h.deliveries {
+		if delivery.Route == route {
+			h.t.Errorf("routertest: expected no delivery to %s, got %s", route, h.describe(route))
+			return
+		}
+	}
+}
+
+func (h *Harness[T]) describe(route string) string {
+	var result []string
+	for _, delivery := range h.deliveries {
+		if delivery.Route == route {
+			result = append(result, fmt.Sprintf("%+v", delivery.Data))
+		}
+	}
+	if len(result) == 0 {
+		return "nothing"
+	}
+	return fmt.Sprint(result)
+}
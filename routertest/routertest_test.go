@@ -0,0 +1,177 @@
+package routertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	bot "github.com/greek-milk-bot/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试同步投递按队列顺序处理转发链
+func TestHarnessForward(t *testing.T) {
+	h := New[string](t)
+	sender := h.AddRoute("sender")
+	var relay *bot.Route[string]
+	relay = h.Handle("relay", func(header bot.RoutePacketHeader, data string) error {
+		relay.SendForward("receiver", &header, data+"!")
+		return nil
+	})
+	h.AddRoute("receiver")
+
+	sender.Send("relay", "a")
+	sender.Send("relay", "b")
+	assert.Equal(t, 4, h.Flush())
+
+	assert.Equal(t, []string{"relay", "relay", "receiver", "receiver"}, routes(h.Deliveries()))
+	delivery := h.ExpectDelivered("receiver", Data("a!"))
+	assert.Equal(t, []string{"sender", "relay"}, delivery.Header.Stack)
+	h.ExpectDelivered("receiver", Data("b!"))
+	h.ExpectNoDelivery("receiver")
+	h.ExpectNoDelivery("sender")
+}
+
+// 测试定时发送与重试按手动时钟触发
+func TestHarnessClock(t *testing.T) {
+	h := New[string](t, bot.WithRetryPolicy[string](bot.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		Multiplier:     2,
+	}))
+	sender := h.AddRoute("sender")
+	attempts := 0
+	h.Handle("flaky", func(header bot.RoutePacketHeader, data string) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("busy")
+		}
+		return nil
+	})
+
+	sender.SendAfter(time.Minute, "flaky", "later")
+	h.ExpectNoDelivery("flaky")
+	h.Advance(time.Minute)
+	h.ExpectDelivered("flaky", Data("later"))
+
+	// 重试间隔依次为 1s、2s
+	attempts = 0
+	sender.SendReliable("flaky", "retry")
+	h.ExpectDelivered("flaky", Data("retry"))
+	h.Advance(time.Second)
+	h.ExpectDelivered("flaky", Data("retry"))
+	h.Advance(time.Second)
+	h.ExpectNoDelivery("flaky")
+	h.Advance(time.Second)
+	h.ExpectDelivered("flaky", From[string]("sender"))
+	assert.Equal(t, 3, attempts)
+}
+
+// 测试限流延迟在推进时钟后投递
+func TestHarnessRateLimit(t *testing.T) {
+	h := New[int](t, bot.WithRateLimit[int](bot.RateLimitDest, "adapter", bot.RateLimit{
+		Rate:  1,
+		Burst: 1,
+		Mode:  bot.RateLimitDelay,
+	}))
+	sender := h.AddRoute("sender")
+	h.AddRoute("adapter")
+	for i := 1; i <= 3; i++ {
+		sender.Send("adapter", i)
+	}
+	h.ExpectDelivered("adapter", Data(1))
+	h.ExpectNoDelivery("adapter")
+	h.Advance(time.Second)
+	h.ExpectDelivered("adapter", Data(2))
+	h.ExpectNoDelivery("adapter")
+	h.Advance(time.Second)
+	h.ExpectDelivered("adapter", Any[int]())
+}
+
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// 测试断言失败时的报告
+func TestHarnessFailures(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	h := New[string](tb)
+	sender := h.AddRoute("sender")
+	h.AddRoute("receiver")
+
+	h.ExpectDelivered("receiver", Any[string]())
+	sender.Send("receiver", "unexpected")
+	h.ExpectNoDelivery("receiver")
+	h.ExpectDelivered("receiver", Data("other"))
+	assert.Equal(t, []string{
+		"routertest: expected delivery to receiver, got nothing",
+		"routertest: expected no delivery to receiver, got [unexpected]",
+		"routertest: expected delivery to receiver, got [unexpected]",
+	}, tb.errors)
+}
+
+func routes[T any](deliveries []Delivery[T]) []string {
+	var result []string
+	for _, delivery := range deliveries {
+		result = append(result, delivery.Route)
+	}
+	return result
+}
+
+// 测试同步投递的队列不受容量限制
+func TestHarnessUnboundedQueue(t *testing.T) {
+	h := New[int](t)
+	sender := h.AddRoute("sender")
+	var fanout *bot.Route[int]
+	fanout = h.Handle("fanout", func(header bot.RoutePacketHeader, data int) error {
+		// 处理器中发送超过队列容量的包
+		for i := range 100 {
+			fanout.Send("receiver", i)
+		}
+		return nil
+	})
+	h.AddRoute("receiver")
+
+	for i := range 65 {
+		sender.Send("receiver", i)
+	}
+	sender.Send("fanout", 0)
+	assert.Equal(t, 166, h.Flush())
+	deliveries := h.Deliveries()
+	assert.Len(t, deliveries, 166)
+	assert.Equal(t, 64, deliveries[64].Data)
+	assert.Equal(t, "fanout", deliveries[65].Route)
+}
+
+// 测试同步投递下请求在 Flush 中完成应答
+func TestHarnessRequest(t *testing.T) {
+	h := New[string](t)
+	client := h.AddRoute("client")
+	var server *bot.Route[string]
+	server = h.Handle("server", func(header bot.RoutePacketHeader, data string) error {
+		return server.Reply(header, data+" pong")
+	})
+	h.AddRoute("slow")
+
+	reply, err := client.Request(context.Background(), "server", "ping")
+	assert.NoError(t, err)
+	assert.Equal(t, "ping pong", reply)
+	h.ExpectDelivered("server", Data("ping"))
+
+	_, err = client.Request(context.Background(), "missing", "ping")
+	assert.EqualError(t, err, "request dropped: no_route")
+
+	// 未应答的请求立即返回错误而不是等待超时
+	_, err = client.Request(context.Background(), "slow", "ping")
+	assert.EqualError(t, err, "request not answered during flush")
+	h.ExpectDelivered("slow", Data("ping"))
+}
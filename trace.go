@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// TraceContext 链路信息，随包头在路由之间传递
+type TraceContext struct {
+	TraceID  string `json:"trace_id,omitempty"`
+	SpanID   string `json:"span_id,omitempty"`
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+// 以当前链路为父节点创建子节点，没有链路时开启新的链路
+func (t TraceContext) child() TraceContext {
+	traceID := t.TraceID
+	if traceID == "" {
+		traceID = fmt.Sprintf("%016x%016x", rand.Uint64(), rand.Uint64())
+	}
+	return TraceContext{
+		TraceID:  traceID,
+		SpanID:   fmt.Sprintf("%016x", rand.Uint64()),
+		ParentID: t.SpanID,
+	}
+}
+
+type traceContextKey struct{}
+
+// ContextWithTrace 将链路信息放入 ctx
+func ContextWithTrace(ctx context.Context, trace TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace)
+}
+
+// TraceFromContext 从 ctx 中读取链路信息
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	trace, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return trace, ok
+}
+
+// Span 链路中的一段
+//
+// 每个包进入队列到被路由器取出记为一个 route 段，
+// 每个处理器处理该包记为一个 deliver 段，其父节点为对应的 route 段
+type Span struct {
+	TraceID  string      `json:"trace_id"`
+	SpanID   string      `json:"span_id"`
+	ParentID string      `json:"parent_id,omitempty"`
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Src      string      `json:"src"`
+	Dest     string      `json:"dest"`
+	Route    string      `json:"route,omitempty"` // 仅 deliver 段有效
+	Stack    []string    `json:"stack"`
+	Hops     []time.Time `json:"hops"`
+	Start    time.Time   `json:"start"`
+	End      time.Time   `json:"end"`
+}
+
+const (
+	SpanNameRoute   = "route"
+	SpanNameDeliver = "deliver"
+)
+
+// Tracer 链路记录器，实现需要保证并发安全
+type Tracer interface {
+	Record(span Span)
+}
+
+// WithTracer 设置路由器的链路记录器
+func WithTracer[T any](tracer Tracer) RouterOption[T] {
+	return func(r *Router[T]) {
+		r.tracer = tracer
+	}
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Record(Span) {}
+
+func newSpan(name string, header RoutePacketHeader, start, end time.Time) Span {
+	return Span{
+		TraceID:  header.Trace.TraceID,
+		SpanID:   header.Trace.SpanID,
+		ParentID: header.Trace.ParentID,
+		Name:     name,
+		Type:     header.Type.String(),
+		Src:      header.Src,
+		Dest:     header.Dest,
+		Stack:    header.Stack,
+		Hops:     header.Hops,
+		Start:    start,
+		End:      end,
+	}
+}
+
+// JSONLinesTracer 将每个段以一行 JSON 写出
+type JSONLinesTracer struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+func NewJSONLinesTracer(w io.Writer) *JSONLinesTracer {
+	return &JSONLinesTracer{encoder: json.NewEncoder(w)}
+}
+
+func (t *JSONLinesTracer) Record(span Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.encoder.Encode(span); err != nil && t.err == nil {
+		t.err = err
+	}
+}
+
+// Err 返回第一次写出失败的错误
+func (t *JSONLinesTracer) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/greek-milk-bot/core/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryTracer struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+func (m *memoryTracer) Record(span Span) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = append(m.spans, span)
+}
+
+func (m *memoryTracer) Spans() []Span {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Span(nil), m.spans...)
+}
+
+// 测试链路在转发与新发送之间传递
+func TestTracePropagation(t *testing.T) {
+	tracer := &memoryTracer{}
+	router := NewRouter[string](64, WithTracer[string](tracer))
+	go router.Run()
+	defer router.Stop()
+
+	routeA, _ := router.AddRoute("A")
+	routeB, _ := router.AddRoute("B")
+	routeC, _ := router.AddRoute("C")
+	routeD, _ := router.AddRoute("D")
+
+	routeB.HandlerFunc(func(header RoutePacketHeader, data string) {
+		routeB.SendForward("C", &header, data)
+	})
+	routeC.HandlerFunc(func(header RoutePacketHeader, data string) {
+		assert.Equal(t, []string{"A", "B"}, header.Stack)
+		assert.Len(t, header.Hops, 2)
+		assert.False(t, header.Hops[1].Before(header.Hops[0]))
+		routeC.SendContext(ContextWithTrace(context.Background(), header.Trace), "D", "reply")
+	})
+	done := make(chan RoutePacketHeader, 1)
+	routeD.HandlerFunc(func(header RoutePacketHeader, data string) {
+		done <- header
+	})
+
+	routeA.Send("B", "hello")
+	last := <-done
+	time.Sleep(50 * time.Millisecond)
+
+	spans := tracer.Spans()
+	assert.Len(t, spans, 6)
+	byID := make(map[string]Span)
+	for _, span := range spans {
+		assert.Equal(t, last.Trace.TraceID, span.TraceID)
+		byID[span.SpanID] = span
+	}
+	// D 的投递段沿父节点回溯：deliver(D) -> route(C->D) -> deliver(C) -> route(B->C) -> deliver(B) -> route(A->B)
+	var chain []string
+	span, ok := byID[last.Trace.SpanID]
+	for ok {
+		chain = append(chain, span.Name+":"+span.Dest)
+		span, ok = byID[span.ParentID]
+	}
+	assert.Equal(t, []string{
+		"deliver:D", "route:D", "deliver:C", "route:C", "deliver:B", "route:B",
+	}, chain)
+}
+
+// 测试 JSON Lines 输出
+func TestJSONLinesTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewJSONLinesTracer(&buf)
+	tracer.Record(Span{TraceID: "t1", SpanID: "s1", Name: SpanNameRoute})
+	tracer.Record(Span{TraceID: "t1", SpanID: "s2", ParentID: "s1", Name: SpanNameDeliver, Route: "B"})
+	assert.NoError(t, tracer.Err())
+
+	scanner := bufio.NewScanner(&buf)
+	var spans []Span
+	for scanner.Scan() {
+		var span Span
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		spans = append(spans, span)
+	}
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "s1", spans[1].ParentID)
+	assert.Equal(t, "B", spans[1].Route)
+}
+
+type traceEchoPlugin struct {
+	boot     chan models.PluginBus
+	received chan models.PluginBus
+}
+
+func (p *traceEchoPlugin) Boot(bus models.PluginBus) error {
+	if p.boot != nil {
+		p.boot <- bus
+	}
+	return nil
+}
+
+func (p *traceEchoPlugin) ReceiveMessage(bus models.PluginBus, msg models.WithSrcPacket[models.Message]) error {
+	p.received <- bus
+	return nil
+}
+
+// 测试链路通过插件总线传递
+func TestTraceThroughPluginBus(t *testing.T) {
+	sender := &traceEchoPlugin{boot: make(chan models.PluginBus, 1), received: make(chan models.PluginBus, 1)}
+	receiver := &traceEchoPlugin{received: make(chan models.PluginBus, 1)}
+	bot, err := NewGreekMilkBot(sender, receiver)
+	assert.NoError(t, err)
+	tracer := &memoryTracer{}
+	assert.NoError(t, bot.WithRouterOptions(WithTracer[models.Packet](tracer)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bot.Run(ctx)
+	}()
+
+	bus := <-sender.boot
+	message := models.Packet{
+		Dest: "1",
+		Type: models.PacketTypeEvent,
+		Data: &models.PacketEvent{Type: models.EventTypeMessage, Data: &models.Message{ID: "m1"}},
+	}
+	assert.NoError(t, bus.SendPacket(message))
+	receivedBus := <-receiver.received
+	trace, ok := TraceFromContext(receivedBus)
+	assert.True(t, ok)
+	assert.NotEmpty(t, trace.TraceID)
+
+	// 接收方通过收到的总线应答，链路保持一致
+	message.Dest = "0"
+	assert.NoError(t, receivedBus.SendPacket(message))
+	replyBus := <-sender.received
+	replyTrace, ok := TraceFromContext(replyBus)
+	assert.True(t, ok)
+	assert.Equal(t, trace.TraceID, replyTrace.TraceID)
+}